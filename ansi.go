@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/donnybeelo/fireplace/fireplace"
+	"github.com/gdamore/tcell/v2"
+)
+
+// frameANSI renders the fires as they stand, without any overlays, to text
+// with true-color escape codes, or to plain characters in ASCII mode
+func frameANSI() []byte {
+	w, h := screen.Size()
+	b := fireplace.NewBuffer(w, h)
+	for _, hr := range hearths {
+		hr.draw(b)
+	}
+
+	var out bytes.Buffer
+	for y := range h {
+		var line bytes.Buffer
+		last := tcell.StyleDefault
+		for x := range w {
+			str, style, _ := b.Get(x, y)
+			if !asciiMode && style != last {
+				fg, bg, _ := style.Decompose()
+				line.WriteString(sgrColor(38, fg) + sgrColor(48, bg))
+				last = style
+			}
+			line.WriteString(str)
+		}
+		if asciiMode {
+			out.WriteString(strings.TrimRight(line.String(), " "))
+		} else {
+			line.WriteString("\x1b[0m")
+			out.Write(line.Bytes())
+		}
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// sgrColor returns the escape code setting c as the foreground (base 38) or
+// background (base 48) color
+func sgrColor(base int, c tcell.Color) string {
+	if c == tcell.ColorDefault {
+		return fmt.Sprintf("\x1b[%dm", base+1)
+	}
+	r, g, b := c.RGB()
+	return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", base, r, g, b)
+}
+
+// copyFrame puts the current frame on the clipboard through the terminal,
+// with OSC 52, which works over SSH too. Terminals that can't take it get
+// the frame written to a file in the working directory instead.
+func copyFrame() {
+	frame := frameANSI()
+	if clipboardTerminal() {
+		screen.SetClipboard(frame)
+		showStatus("frame copied to the clipboard")
+		return
+	}
+
+	name := time.Now().Format("fireplace-20060102-150405.ans")
+	if err := os.WriteFile(name, frame, 0o644); err != nil {
+		showStatus("couldn't save the frame: " + err.Error())
+		return
+	}
+	showStatus("no clipboard here, frame saved to " + name)
+}
+
+// clipboardTerminal guesses whether the terminal accepts OSC 52, which tcell
+// only sends to xterm-like terminals and which nothing ever acknowledges.
+// --safe doesn't risk it.
+func clipboardTerminal() bool {
+	if safeMode {
+		return false
+	}
+	switch term := os.Getenv("TERM"); {
+	case term == "", term == "dumb", term == "linux":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Flags left out of the usage message, for development use only
+var hiddenFlags = map[string]bool{
+	"bench-frames": true,
+}
+
+// printUsage prints the usage message like the flag package does, without
+// the hidden flags
+func printUsage() {
+	out := flag.CommandLine.Output()
+	visible := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	visible.SetOutput(out)
+	flag.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			// Var takes the default from the value, which parsing may
+			// already have changed
+			visible.Var(f.Value, f.Name, f.Usage)
+			visible.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+
+	fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+	visible.PrintDefaults()
+}
+
+// runBench simulates and draws n frames back to back on the headless screen,
+// with no ticker holding them to the frame rate, and prints how long they
+// took. It times the whole pipeline end to end, drawing included.
+func runBench(n int) {
+	start := time.Now()
+	for range n {
+		stepFires()
+		drawFrame()
+	}
+	elapsed := time.Since(start)
+
+	w, h := screen.Size()
+	fmt.Printf("%d frames of %dx%d in %v (%v per frame)\n", n, w, h, elapsed, elapsed/time.Duration(n))
+}
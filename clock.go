@@ -0,0 +1,108 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var (
+	clockMode     bool   // Whether to show the time over the fire
+	clockDate     bool   // Whether to show the date under the time
+	clockPosition string // Where the clock goes: "top", "center" or "bottom"
+)
+
+// Block font for the clock, three pixels wide and five tall. Each pixel is
+// drawn two cells wide so the digits come out roughly square.
+var clockFont = map[rune][5]string{
+	'0': {"###", "# #", "# #", "# #", "###"},
+	'1': {" # ", "## ", " # ", " # ", "###"},
+	'2': {"###", "  #", "###", "#  ", "###"},
+	'3': {"###", "  #", "###", "  #", "###"},
+	'4': {"# #", "# #", "###", "  #", "  #"},
+	'5': {"###", "#  ", "###", "  #", "###"},
+	'6': {"###", "#  ", "###", "# #", "###"},
+	'7': {"###", "  #", "  #", "  #", "  #"},
+	'8': {"###", "# #", "###", "# #", "###"},
+	'9': {"###", "# #", "###", "  #", "###"},
+	':': {" ", "#", " ", "#", " "},
+}
+
+// Color of the clock's digits and date
+var clockInk = tcell.NewRGBColor(230, 220, 200)
+
+// drawClock draws the time in block digits over the fire, on a darkened
+// panel that keeps it legible against bright flames while a hint of the
+// fire glows through
+func drawClock() {
+	now := time.Now()
+	text := now.Format("15:04")
+	date := now.Format("Mon 2 Jan")
+
+	// Lay the glyphs out side by side with a pixel of space between them
+	rows := [5][]bool{}
+	for i, r := range text {
+		if i > 0 {
+			for y := range rows {
+				rows[y] = append(rows[y], false)
+			}
+		}
+		for y, line := range clockFont[r] {
+			for _, p := range line {
+				rows[y] = append(rows[y], p == '#')
+			}
+		}
+	}
+
+	w := len(rows[0])*2 + 4 // Two cells a pixel and a margin each side
+	h := len(rows) + 2
+	if clockDate {
+		h += 2
+	}
+
+	screenW, screenH := screen.Size()
+	left := max((screenW-w)/2, 0)
+	areaTop := drawTop()
+	top := areaTop + 1
+	switch clockPosition {
+	case "center":
+		top = areaTop + (screenH-areaTop-h)/2
+	case "bottom":
+		top = screenH - h - 1
+	}
+	top = max(top, areaTop)
+
+	for y := range h {
+		for x := range w {
+			screen.SetContent(left+x, top+y, ' ', nil, helpStyle(left+x, top+y))
+		}
+	}
+
+	for y, row := range rows {
+		for px, on := range row {
+			if !on {
+				continue
+			}
+			for i := range 2 {
+				x, sy := left+2+px*2+i, top+1+y
+				if asciiMode {
+					screen.SetContent(x, sy, '#', nil, tcell.StyleDefault)
+				} else {
+					screen.SetContent(x, sy, ' ', nil, tcell.StyleDefault.Background(clockInk))
+				}
+			}
+		}
+	}
+
+	if clockDate {
+		x := left + (w-len(date))/2
+		y := top + h - 2
+		for i, r := range date {
+			style := helpStyle(x+i, y)
+			if !asciiMode {
+				style = style.Foreground(clockInk)
+			}
+			screen.SetContent(x+i, y, r, nil, style)
+		}
+	}
+}
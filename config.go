@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/donnybeelo/fireplace/fireplace"
+)
+
+// Config files hold one "name = value" setting per line, where name is any
+// command line flag without its dashes. Blank lines and lines starting with
+// '#' are ignored. Flags given on the command line always take precedence.
+
+var (
+	configPath     string               // Config file in use ("" if none)
+	cliFlags       map[string]bool      // Flags set explicitly on the command line
+	fileFlags      map[string]bool      // Flags set by the config file last loaded
+	regionSize     = pairFlag{sep: "x"} // Size of the render region (0 = full screen)
+	regionOrigin   = pairFlag{sep: ","} // Top-left corner of the render region
+	bottomMargin   int                  // Rows left empty under the fire
+	reflectionRows int                  // Rows under the fire its reflection takes
+	gridScale      = 1                  // Screen cells across and down each simulated cell covers
+)
+
+// Coarsest --grid-scale, past which the flames are too blocky to read
+const maxGridScale = 4
+
+// pairFlag is a flag value holding two non-negative integers joined by sep,
+// such as "80x24" or "10,5"
+type pairFlag struct {
+	x, y int
+	sep  string
+}
+
+func (p *pairFlag) String() string {
+	if p.x == 0 && p.y == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d%s%d", p.x, p.sep, p.y)
+}
+
+func (p *pairFlag) Set(s string) error {
+	// The empty string is how String shows 0 and 0
+	if s == "" {
+		p.x, p.y = 0, 0
+		return nil
+	}
+	a, b, ok := strings.Cut(s, p.sep)
+	x, errX := strconv.Atoi(strings.TrimSpace(a))
+	y, errY := strconv.Atoi(strings.TrimSpace(b))
+	if !ok || errX != nil || errY != nil || x < 0 || y < 0 {
+		return fmt.Errorf("expected two numbers like 10%s5", p.sep)
+	}
+	p.x, p.y = x, y
+	return nil
+}
+
+// glowFlag is a flag value holding how much a log's red, green and blue rise
+// per unit of heat around it, such as "5,2,0"
+type glowFlag [3]float64
+
+func (g *glowFlag) String() string {
+	return fmt.Sprintf("%g,%g,%g", g[0], g[1], g[2])
+}
+
+func (g *glowFlag) Set(s string) error {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return fmt.Errorf("expected three numbers like 5,2,0")
+	}
+	var v glowFlag
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || f < 0 || f > maxLogGlow {
+			return fmt.Errorf("expected three numbers from 0 to %g like 5,2,0", float64(maxLogGlow))
+		}
+		v[i] = f
+	}
+	*g = v
+	return nil
+}
+
+// defaultConfigPath returns the per-user config file location
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "fireplace", "config")
+}
+
+// loadConfig reads the config file at path and applies each setting that
+// wasn't given on the command line. A missing file is only an error when
+// required is set.
+func loadConfig(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !required && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	type setting struct {
+		line        int
+		name, value string
+	}
+
+	// Parse the whole file before applying anything so a typo doesn't leave
+	// the settings half-updated
+	var settings []setting
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return fmt.Errorf("%s:%d: expected name = value", path, n)
+		}
+		if name == "config" || flag.Lookup(name) == nil {
+			return fmt.Errorf("%s:%d: unknown setting %q", path, n, name)
+		}
+		settings = append(settings, setting{n, name, strings.TrimSpace(value)})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	fileFlags = map[string]bool{}
+	for _, s := range settings {
+		fileFlags[s.name] = true
+		if cliFlags[s.name] {
+			continue
+		}
+		if err := flag.Set(s.name, s.value); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, s.line, err)
+		}
+	}
+	return nil
+}
+
+// validateSettings rejects flag values that are out of range
+func validateSettings() error {
+	if logsWanted < 0 {
+		return fmt.Errorf("--logs must not be negative")
+	}
+	if logSpacing <= 0 {
+		return fmt.Errorf("--log-spacing must be positive")
+	}
+	if coreLogs < 0 {
+		return fmt.Errorf("--core-logs must not be negative")
+	}
+	if logLayout != "hearth" && logLayout != "teepee" {
+		return fmt.Errorf("--layout must be hearth or teepee, not %q", logLayout)
+	}
+	if fireSpanRatio <= 0 || fireSpanRatio > 1 {
+		return fmt.Errorf("--fire-span must be above 0 and at most 1")
+	}
+	if coreSpread <= 0 {
+		return fmt.Errorf("--core-spread must be positive")
+	}
+	if warmupTicks < 0 {
+		return fmt.Errorf("--warmup must not be negative")
+	}
+	if heatSources < 0 {
+		return fmt.Errorf("--heat-sources must not be negative")
+	}
+	if err := checkQuitKeys(); err != nil {
+		return err
+	}
+	if refuelDepth < 0 || refuelDepth > 1 {
+		return fmt.Errorf("--refuel-depth must be between 0 and 1")
+	}
+	// Heat indexes the 37-entry colors slice
+	if maxHeat < 1 || maxHeat > 36 {
+		return fmt.Errorf("--max-heat must be between 1 and 36")
+	}
+	if intensity < 0 || intensity > maxIntensity {
+		return fmt.Errorf("--intensity must be between 0 and %g", maxIntensity)
+	}
+	if baseCrackles < 0 || baseCrackles > maxCrackles {
+		return fmt.Errorf("--crackle-rate must be between 0 and %g", maxCrackles)
+	}
+	if timeScale < minTimeScale || timeScale > maxTimeScale {
+		return fmt.Errorf("--time-scale must be between %g and %g", minTimeScale, maxTimeScale)
+	}
+	if breatheDepth < 0 || breatheDepth > 1 {
+		return fmt.Errorf("--breathe-depth must be between 0 and 1")
+	}
+	if turbulence < 0 || turbulence > 2 {
+		return fmt.Errorf("--turbulence must be between 0 and 2")
+	}
+	if convection < 0 || convection > 1 {
+		return fmt.Errorf("--convection must be between 0 and 1")
+	}
+	if flueX < 0 || flueX > 1 {
+		return fmt.Errorf("--flue-x must be between 0 and 1")
+	}
+	if edgeFalloff < 0 || edgeFalloff > 20 {
+		return fmt.Errorf("--edge-falloff must be between 0 and 20")
+	}
+	if texture < 0 || texture > 2 {
+		return fmt.Errorf("--texture must be between 0 and 2")
+	}
+	if emberFloor < 0 || emberFloor > 36 {
+		return fmt.Errorf("--floor must be between 0 and 36")
+	}
+	if !slices.Contains(fireplace.FuelProfiles, fuelProfile) {
+		return fmt.Errorf("--fuel-profile must be one of %v", fireplace.FuelProfiles)
+	}
+	if !slices.Contains(fireplace.WoodColors, woodColor) {
+		return fmt.Errorf("--wood-color must be one of %v", fireplace.WoodColors)
+	}
+	if blockChar != "upper" && blockChar != "lower" {
+		return fmt.Errorf("--block must be upper or lower, not %q", blockChar)
+	}
+	if direction != "up" && direction != "down" {
+		return fmt.Errorf("--direction must be up or down, not %q", direction)
+	}
+	if !slices.Contains(sampleRates, sampleRate) {
+		return fmt.Errorf("--sample-rate must be one of %v", sampleRates)
+	}
+	if volume < 0 || volume > 1 {
+		return fmt.Errorf("--volume must be between 0 and 1")
+	}
+	if rumbleWidth < 0 || rumbleWidth > 1 {
+		return fmt.Errorf("--rumble-width must be between 0 and 1")
+	}
+	if rumbleQuality != "high" && rumbleQuality != "low" {
+		return fmt.Errorf("--rumble-quality must be high or low, not %q", rumbleQuality)
+	}
+	if emberRate < 0 || emberSpeed < 0 || emberLife < 0 {
+		return fmt.Errorf("--ember-rate, --ember-speed and --ember-life must not be negative")
+	}
+	if inlineRows < 0 {
+		return fmt.Errorf("--inline must not be negative")
+	}
+	if bottomMargin < 0 {
+		return fmt.Errorf("--bottom-margin must not be negative")
+	}
+	if reflectionRows < 0 {
+		return fmt.Errorf("--reflection must not be negative")
+	}
+	if gridScale < 1 || gridScale > maxGridScale {
+		return fmt.Errorf("--grid-scale must be between 1 and %d", maxGridScale)
+	}
+	if woodLight < minWoodLight || woodLight > maxWoodLight {
+		return fmt.Errorf("--wood-brightness must be between %g and %g", minWoodLight, maxWoodLight)
+	}
+	if stormIntensity < 0 || stormIntensity > 2 {
+		return fmt.Errorf("--storm-intensity must be between 0 and 2")
+	}
+	if math.IsNaN(baseWind) || baseWind < -maxWind || baseWind > maxWind {
+		return fmt.Errorf("--wind must be between %g and %g", -maxWind, maxWind)
+	}
+	if vignette < 0 || vignette > 1 {
+		return fmt.Errorf("--vignette must be between 0 and 1")
+	}
+	if blendCap <= 0 || blendCap > 1 {
+		return fmt.Errorf("--saturation-cap must be above 0 and at most 1")
+	}
+	if visualizerStrength < 0 || visualizerStrength > 1 {
+		return fmt.Errorf("--visualizer-strength must be between 0 and 1")
+	}
+	if crackTone < minCrackTone || crackTone > maxCrackTone {
+		return fmt.Errorf("--crack-tone must be between %g and %g", minCrackTone, maxCrackTone)
+	}
+	if crackDecay < minCrackDecay || crackDecay > maxCrackDecay {
+		return fmt.Errorf("--crack-decay must be between %g and %g", minCrackDecay, maxCrackDecay)
+	}
+	if crackleJitter < 0 || crackleJitter > 1 {
+		return fmt.Errorf("--crackle-jitter must be between 0 and 1")
+	}
+	if crackSpread < 0 || crackSpread > 1 {
+		return fmt.Errorf("--crack-spread must be between 0 and 1")
+	}
+	if !slices.Contains(lightFormats, lightFormat) {
+		return fmt.Errorf("--light-format must be one of %v", lightFormats)
+	}
+	if clockPosition != "top" && clockPosition != "center" && clockPosition != "bottom" {
+		return fmt.Errorf("--clock-position must be top, center or bottom, not %q", clockPosition)
+	}
+	if colorMode != "auto" && colorMode != "truecolor" {
+		return fmt.Errorf("--colors must be auto or truecolor, not %q", colorMode)
+	}
+	if exportFrames < 1 {
+		return fmt.Errorf("--frames must be at least 1")
+	}
+	if cellSize.x < 1 || cellSize.y < 1 {
+		return fmt.Errorf("--cell must be at least 1x1")
+	}
+	if watchPalette && paletteFile == "" {
+		return fmt.Errorf("--watch needs a --palette-file to watch")
+	}
+	if _, ok := lookupPalette(paletteName); !ok {
+		return fmt.Errorf("--palette: unknown palette %q", paletteName)
+	}
+	return nil
+}
+
+// applyConfig derives the runtime state from the current settings. It runs
+// at startup and again whenever the config is reloaded, so it must only
+// touch state that can change between frames.
+func applyConfig() {
+	loadPalette()
+	setCrackleScale(baseCrackles)
+	setAudioLevel(volume)
+}
+
+// reloadConfig re-reads the config file in response to SIGHUP. The previous
+// settings stay in effect if the file can't be read or any of it is rejected.
+func reloadConfig() {
+	if configPath == "" {
+		return
+	}
+
+	// The file is only validated once all of it is set, so a rejected one is
+	// rolled back flag by flag
+	saved := map[string]string{}
+	flag.VisitAll(func(f *flag.Flag) { saved[f.Name] = f.Value.String() })
+	savedFileFlags := fileFlags
+	for _, step := range []func() error{
+		func() error { return loadConfig(configPath, true) },
+		applyTheme, validateSettings, loadPaletteFile,
+	} {
+		if err := step(); err != nil {
+			for name, value := range saved {
+				if flag.Lookup(name).Value.String() != value {
+					flag.Set(name, value)
+				}
+			}
+			fileFlags = savedFileFlags
+			logger.Warn("config reload failed, keeping the previous settings", "err", err)
+			return
+		}
+	}
+
+	// --safe and --campfire rework other settings once, at startup, so
+	// changing them takes a restart. They still win over the file as they
+	// did then.
+	for _, name := range []string{"safe", "campfire"} {
+		if value := flag.Lookup(name).Value.String(); value != saved[name] {
+			flag.Set(name, saved[name])
+			logger.Warn("config reload can't change this setting, restart to apply it", "setting", name, "value", value)
+		}
+	}
+	applySafeMode()
+	if flag.Lookup("campfire").Value.String() == "true" {
+		useCampfire()
+	}
+	applyConfig()
+	logger.Info("config reloaded", "path", configPath, "palette", paletteName)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A control socket takes one command per line and answers each with "ok" or
+// "error: " and the reason:
+//
+//	stoke            flare the fire up, like a loud crack
+//	mute             mute or unmute the sound
+//	pause            freeze the fire on its current frame, or set it going again
+//	intensity 1.3    scale how much fuel the fire gets, from 0 to 2
+//	wind -0.5        blow the flames steadily, from -2 to the left to 2 to the right
+//	palette cb       crossfade to a built-in palette
+//	mood coals       ease into a mood, or the next one without a name
+//	quit             exit
+//
+// Commands are run by the main loop between frames, through the same
+// functions the keys use.
+
+// controlRequest is one command from a control socket client
+type controlRequest struct {
+	line  string
+	reply chan string // Receives the answer to send back
+}
+
+var controlRequests = make(chan controlRequest)
+
+// startControl listens for control clients on a Unix socket at path,
+// replacing a socket left behind by an earlier run. The caller closes the
+// listener, which removes the socket.
+func startControl(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer stopOnPanic("control socket")
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveControl(conn)
+		}
+	}()
+	return ln, nil
+}
+
+// serveControl hands each command from a client to the main loop and writes
+// back the answer
+func serveControl(conn net.Conn) {
+	defer stopOnPanic("control client")
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		reply := make(chan string, 1)
+		controlRequests <- controlRequest{line, reply}
+		fmt.Fprintln(conn, <-reply)
+	}
+}
+
+// runControl applies a control command and returns the answer for the
+// client, and whether the program should quit
+func runControl(line string) (string, bool) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "stoke":
+		stoke()
+	case "mute":
+		toggleMute()
+	case "pause":
+		togglePause()
+	case "intensity":
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil || v < 0 || v > maxIntensity {
+			return fmt.Sprintf("error: intensity needs a number from 0 to %g", maxIntensity), false
+		}
+		intensity = v
+		notify(fmt.Sprintf("intensity: %g", v))
+	case "palette":
+		if _, ok := lookupPalette(arg); !ok {
+			return fmt.Sprintf("error: unknown palette %q", arg), false
+		}
+		fadeToPalette(arg)
+	case "mood":
+		if arg == "" {
+			nextMood()
+			break
+		}
+		i, err := moodNamed(arg)
+		if err != nil {
+			return "error: " + err.Error(), false
+		}
+		setMood(i)
+	case "quit":
+		return "ok", true
+	case "size", "logs":
+		return "error: the grid only changes size with the terminal", false
+	case "wind":
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil || math.IsNaN(v) || v < -maxWind || v > maxWind {
+			return fmt.Sprintf("error: wind needs a number from %g to %g", -maxWind, maxWind), false
+		}
+		baseWind = v
+		notify(fmt.Sprintf("wind: %g", v))
+	default:
+		return fmt.Sprintf("error: unknown command %q", name), false
+	}
+	return "ok", false
+}
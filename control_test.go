@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestControlWind sends wind commands as a control client would and checks
+// only those in range change the base wind, which then blows with or without
+// a storm
+func TestControlWind(t *testing.T) {
+	savedBase, savedWind, savedStorm := baseWind, wind, stormMode
+	t.Cleanup(func() { baseWind, wind, stormMode = savedBase, savedWind, savedStorm })
+	baseWind, stormMode = 0, false
+
+	tests := []struct {
+		line  string
+		ok    bool
+		after float64
+	}{
+		{"wind 0.5", true, 0.5},
+		{"wind -2", true, -2},
+		{"wind 2", true, 2},
+		{"wind 2.01", false, 2},
+		{"wind -7", false, 2},
+		{"wind NaN", false, 2},
+		{"wind gale", false, 2},
+		{"wind", false, 2},
+		{"wind 0", true, 0},
+	}
+	for _, tt := range tests {
+		reply, quit := runControl(tt.line)
+		if ok := reply == "ok"; ok != tt.ok || quit {
+			t.Errorf("%q answered %q, quit %v", tt.line, reply, quit)
+		}
+		if baseWind != tt.after {
+			t.Errorf("after %q the base wind is %g, want %g", tt.line, baseWind, tt.after)
+		}
+	}
+
+	runControl("wind -1.5")
+	stepStorm()
+	if wind != -1.5 {
+		t.Errorf("without a storm the wind blows %g, want the base wind -1.5", wind)
+	}
+}
+
+// TestStormOverBaseWind blows a storm over a steady wind and checks the
+// flames are pushed by the base wind and the gust together, and the calm
+// between gusts falls back to the base wind
+func TestStormOverBaseWind(t *testing.T) {
+	savedBase, savedWind, savedStorm, savedRng, savedTick := baseWind, wind, stormMode, stormRng, tick
+	t.Cleanup(func() {
+		baseWind, wind, stormMode, stormRng, tick = savedBase, savedWind, savedStorm, savedRng, savedTick
+	})
+	baseWind, stormMode, stormRng, tick = 0.75, true, nil, 0
+
+	gusts, calms := 0, 0
+	for tick = 1; tick <= 2000; tick++ {
+		stepStorm()
+		gust := wind - baseWind
+		if got := gustStrength() * maxGust * 2; math.Abs(got-math.Abs(gust)) > 1e-9 {
+			t.Fatalf("tick %d: the rumble hears a gust of %g, the flames %g", tick, got, gust)
+		}
+		if math.Abs(gust) < 1e-12 {
+			calms++
+		} else {
+			gusts++
+		}
+	}
+	if gusts == 0 || calms == 0 {
+		t.Errorf("%d ticks of gusts and %d of calm, want some of both", gusts, calms)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Background tasks that panic are stopped rather than allowed to take the
+// process down with the terminal still in raw mode. What went wrong is kept
+// until the terminal is restored, since writing it out mid-frame would only
+// garble the screen.
+var (
+	failuresMu sync.Mutex
+	failures   []string
+)
+
+// recordFailure notes that the named task stopped after a panic
+func recordFailure(name string, v any) {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	failures = append(failures, fmt.Sprintf("%s stopped after a panic: %v", name, v))
+	logger.Error("recovered panic", "task", name, "panic", v)
+}
+
+// stopOnPanic is deferred by background tasks the program runs fine without,
+// such as individual sounds. It recovers a panic, records it and lets the
+// task's goroutine end.
+func stopOnPanic(name string) {
+	if v := recover(); v != nil {
+		recordFailure(name, v)
+	}
+}
+
+// crashGuard is deferred by goroutines that can't be stopped on their own.
+// It restores the terminal and then lets the panic continue.
+func crashGuard() {
+	if v := recover(); v != nil {
+		screen.Fini()
+		panic(v)
+	}
+}
+
+// reportFailures prints the recorded failures, once the terminal is back to
+// normal
+func reportFailures() {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	for _, f := range failures {
+		fmt.Fprintln(os.Stderr, f)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	"github.com/donnybeelo/fireplace/fireplace"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Frames a resize crossfade takes (under half a second at 20 FPS)
+const resizeFadeFrames = 8
+
+var (
+	resizeFrom  *fireplace.Buffer // Fires as they looked before the resize (nil when not fading)
+	resizeFrame int               // Frames into the current resize crossfade
+)
+
+// startResizeFade remembers how the fires looked before a resize replaces
+// them, so the new logs fade in rather than popping into place
+func startResizeFade(old []*hearth) {
+	resizeFrom = nil
+	if len(old) == 0 || asciiMode {
+		return
+	}
+
+	w, h := screen.Size()
+	b := fireplace.NewBuffer(w, h)
+	for _, o := range old {
+		o.draw(b)
+	}
+	resizeFrom = b
+	resizeFrame = 0
+}
+
+// blendResizeFade mixes the frame just drawn with the one from before the
+// resize, letting the old one show through less each frame
+func blendResizeFade() {
+	if resizeFrom == nil {
+		return
+	}
+
+	resizeFrame++
+	if resizeFrame >= resizeFadeFrames {
+		resizeFrom = nil
+		return
+	}
+
+	t := float64(resizeFrame) / resizeFadeFrames
+	w, h := screen.Size()
+	for y := range h {
+		for x := range w {
+			str, style, _ := screen.Get(x, y)
+			_, old, _ := resizeFrom.Get(x, y)
+			fg, bg, _ := style.Decompose()
+			oldFg, oldBg, _ := old.Decompose()
+			if bg == tcell.ColorDefault && oldBg == tcell.ColorDefault {
+				continue
+			}
+
+			r, _ := utf8.DecodeRuneInString(str)
+			style = tcell.StyleDefault.Foreground(mixColors(oldFg, fg, t)).Background(mixColors(oldBg, bg, t))
+			screen.SetContent(x, y, r, nil, style)
+		}
+	}
+}
+
+// mixColors blends from a toward b by t, taking the terminal's default color
+// as black
+func mixColors(a, b tcell.Color, t float64) tcell.Color {
+	if a == tcell.ColorDefault {
+		a = tcell.ColorBlack
+	}
+	if b == tcell.ColorDefault {
+		b = tcell.ColorBlack
+	}
+	r1, g1, b1 := a.RGB()
+	r2, g2, b2 := b.RGB()
+	return tcell.NewRGBColor(
+		int32(float64(r1)+float64(r2-r1)*t),
+		int32(float64(g1)+float64(g2-g1)*t),
+		int32(float64(b1)+float64(b2-b1)*t),
+	)
+}
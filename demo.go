@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// demoMode is whether --demo is still running the fire through its features
+var demoMode bool
+
+// How long each step of the demo shows before the next
+const demoStepTime = 6 * time.Second
+
+// The demo's timeline, which repeats until a key is pressed. Each step is an
+// action the keyboard or the control socket can also take.
+var demoSteps = []struct {
+	label  string
+	action func()
+}{
+	{"next palette", cyclePalette},
+	{"stoke the fire", stoke},
+	{"another log", addLog},
+	{"taller flames", func() { changeFlameHeight(1.5) }},
+	{"shorter flames", func() { changeFlameHeight(1 / 1.5) }},
+	{"campfire", demoCampfire},
+	{"slow motion", func() { changeTimeScale(0.5) }},
+	{"full speed", func() { changeTimeScale(2) }},
+	{"next palette", cyclePalette},
+	{"hearth", demoHearth},
+}
+
+var (
+	demoStep int       // Next step of the timeline to run
+	demoNext time.Time // When it runs
+	demoFrom struct {  // Shape to go back to after the campfire
+		layout     string
+		width      int
+		span, lick float64
+	}
+)
+
+// startDemo runs the first step of the demo straight away
+func startDemo() {
+	demoMode = true
+	demoStep = 0
+	demoNext = time.Now()
+	demoFrom.layout, demoFrom.width = logLayout, hearthWidth
+	demoFrom.span, demoFrom.lick = fireSpanRatio, lickChance
+}
+
+// stepDemo runs the next step of the timeline once its time comes
+func stepDemo() {
+	if !demoMode || time.Now().Before(demoNext) {
+		return
+	}
+
+	s := demoSteps[demoStep]
+	s.action()
+	showStatus(fmt.Sprintf("demo: %s (press any key to take over)", s.label))
+	demoStep = (demoStep + 1) % len(demoSteps)
+	demoNext = time.Now().Add(demoStepTime)
+}
+
+// stopDemo hands the fire back to the keyboard, as it is
+func stopDemo() {
+	demoMode = false
+	showStatus("demo over")
+}
+
+// demoCampfire rebuilds the fires as a campfire
+func demoCampfire() {
+	useCampfire()
+	resize()
+}
+
+// demoHearth rebuilds the fires in the shape they had before the campfire
+func demoHearth() {
+	logLayout, hearthWidth = demoFrom.layout, demoFrom.width
+	fireSpanRatio, lickChance = demoFrom.span, demoFrom.lick
+	resize()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/donnybeelo/fireplace/fireplace"
+)
+
+// A fireEvent is one line of the --events-out stream. Fields that don't
+// apply to its type are left out.
+type fireEvent struct {
+	Type     string    `json:"type"` // "crack", "sizzle", "flare", "gust" or "log-burned"
+	Time     time.Time `json:"time"`
+	Loudness float64   `json:"loudness,omitempty"` // Of a crack or flare, from 0 to 1
+	Spread   float64   `json:"spread,omitempty"`   // Stereo width of a crack, from 0 to 1
+	Wind     float64   `json:"wind,omitempty"`     // Peak push of a gust, negative to the left
+	Seconds  float64   `json:"seconds,omitempty"`  // How long a gust blows for
+	Hearth   int       `json:"hearth,omitempty"`   // Which fire a log burned in, from 1 on the left
+	Log      int       `json:"log,omitempty"`      // Which of its logs, by Log.ID
+}
+
+// Events waiting for the event writer, or nil without --events-out. It's
+// buffered for a burst of cracks, and past that events are dropped, so a
+// slow reader never holds up the sound or the render loop.
+var fireEvents chan fireEvent
+
+// startEvents opens the event stream at target, a file path or fd:N for an
+// inherited file descriptor, and starts writing the events sent to it. A
+// named pipe blocks here until something opens it to read.
+func startEvents(target string) error {
+	var out io.WriteCloser
+	if fd, ok := strings.CutPrefix(target, "fd:"); ok {
+		n, err := strconv.Atoi(fd)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%q is not a file descriptor", fd)
+		}
+		out = os.NewFile(uintptr(n), target)
+	} else {
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+		out = f
+	}
+
+	fireEvents = make(chan fireEvent, 64)
+	go writeEvents(out)
+	return nil
+}
+
+// writeEvents writes each event to out as a line of JSON until a write
+// fails, after which the events are dropped
+func writeEvents(out io.WriteCloser) {
+	defer stopOnPanic("event output")
+	defer out.Close()
+
+	enc := json.NewEncoder(out)
+	for e := range fireEvents {
+		if err := enc.Encode(e); err != nil {
+			logger.Warn("event output stopped", "err", err)
+			return
+		}
+	}
+}
+
+// emitEvent stamps e with the time and passes it to the event writer,
+// dropping it if the writer has fallen behind. It's safe to call from the
+// audio goroutines.
+func emitEvent(e fireEvent) {
+	if fireEvents == nil {
+		return
+	}
+	e.Time = time.Now()
+	select {
+	case fireEvents <- e:
+	default:
+	}
+}
+
+// burnedAway reports which of f's logs have burned away completely, so
+// stepFires can tell when another goes
+func burnedAway(f *fireplace.Fire) []bool {
+	logs := f.Logs()
+	gone := make([]bool, len(logs))
+	for i, l := range logs {
+		gone[i] = l.Burned() >= 1
+	}
+	return gone
+}
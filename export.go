@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var (
+	pngDir       string                            // Directory to write PNG frames to ("" = don't)
+	exportFrames = 100                             // Frames to export
+	cellSize     = pairFlag{x: 8, y: 16, sep: "x"} // Pixels per character in exported images
+)
+
+// exportPNGs simulates and draws the export frames on the headless screen,
+// writing each to frame0001.png, frame0002.png and so on in pngDir
+func exportPNGs() error {
+	if err := os.MkdirAll(pngDir, 0o755); err != nil {
+		return err
+	}
+
+	for i := range exportFrames {
+		stepFires()
+		drawFrame()
+
+		path := filepath.Join(pngDir, fmt.Sprintf("frame%04d.png", i+1))
+		if err := writePNG(path, screenImage()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// screenImage paints the headless screen's contents into an image, each cell
+// a block of cellSize pixels. A half block shows its foreground over its
+// background as on a terminal, as does a lower eighth block over however
+// much of the cell it fills; any other character is only its background,
+// since there's no font to draw it with.
+func screenImage() *image.RGBA {
+	cells, w, h := screen.(tcell.SimulationScreen).GetContents()
+	cw, ch := cellSize.x, cellSize.y
+	img := image.NewRGBA(image.Rect(0, 0, w*cw, h*ch))
+
+	for i, c := range cells {
+		fg, bg, _ := c.Style.Decompose()
+		top, bottom := pixelColor(bg), pixelColor(bg)
+		split := ch / 2 // First pixel row of the bottom color
+		if len(c.Runes) > 0 {
+			switch r := c.Runes[0]; {
+			case r == '▀':
+				top = pixelColor(fg)
+			case r >= '▁' && r <= '▇':
+				// '▄' is the half-full one of these
+				bottom = pixelColor(fg)
+				split = ch - int(r-'▁'+1)*ch/8
+			}
+		}
+
+		x0, y0 := (i%w)*cw, (i/w)*ch
+		for y := range ch {
+			px := bottom
+			if y < split {
+				px = top
+			}
+			for x := range cw {
+				img.SetRGBA(x0+x, y0+y, px)
+			}
+		}
+	}
+	return img
+}
+
+// pixelColor converts a cell color, with the terminal default as black
+func pixelColor(c tcell.Color) color.RGBA {
+	if c == tcell.ColorDefault {
+		return color.RGBA{A: 255}
+	}
+	r, g, b := c.RGB()
+	return color.RGBA{uint8(r), uint8(g), uint8(b), 255}
+}
@@ -0,0 +1,38 @@
+package fireplace
+
+import "github.com/gdamore/tcell/v2"
+
+// Glyphs for increasing heat in the monochrome renderer
+const asciiRamp = " .:-=+*#%@"
+
+// drawASCII renders the fire without any color, for terminals or users that
+// don't want escape-coded output. Heat is shown by glyph density and logs
+// as a plain texture under the flames.
+func (f *Fire) drawASCII() {
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			heat := max(f.drawHeat(x, y*2), f.drawHeat(x, y*2+1))
+			var char rune
+			switch {
+			case heat >= 4:
+				char = rune(asciiRamp[clamp(heat, 0, 36)*(len(asciiRamp)-1)/36])
+			case f.isWood(x, y):
+				char = '='
+			default:
+				continue
+			}
+
+			f.setContent(x, y, char, tcell.StyleDefault)
+		}
+	}
+
+	// The floor shows between the logs and flames on it
+	if f.Settings.FloorLine {
+		y := f.floorRow()
+		for x := 0; x < f.width; x++ {
+			if f.cells[y*f.width+x].r == ' ' {
+				f.setContent(x, y, '_', tcell.StyleDefault)
+			}
+		}
+	}
+}
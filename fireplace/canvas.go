@@ -0,0 +1,105 @@
+package fireplace
+
+import "github.com/gdamore/tcell/v2"
+
+// Canvas is a grid of cells a fire can be drawn onto. tcell.Screen is one,
+// and Buffer is an in-memory one for rendering off screen or in tests.
+type Canvas interface {
+	SetContent(x, y int, primary rune, combining []rune, style tcell.Style)
+	Get(x, y int) (str string, style tcell.Style, width int)
+}
+
+// Draw renders the fire onto c with its top-left corner at (left, top)
+func (f *Fire) Draw(c Canvas, left, top int) {
+	f.Render(func(x, y int, fg, bg tcell.Color, r rune) {
+		c.SetContent(left+x, top+y, r, nil, tcell.StyleDefault.Foreground(fg).Background(bg))
+	})
+}
+
+// Buffer is a Canvas held in memory. Cells outside it are ignored when set
+// and empty when read.
+type Buffer struct {
+	width, height int
+	cells         []cell
+}
+
+// NewBuffer returns a w by h buffer of spaces on the default colors
+func NewBuffer(w, h int) *Buffer {
+	b := &Buffer{width: w, height: h, cells: make([]cell, w*h)}
+	for i := range b.cells {
+		b.cells[i] = cell{' ', tcell.StyleDefault}
+	}
+	return b
+}
+
+// Size returns the width and height of the buffer in cells
+func (b *Buffer) Size() (int, int) {
+	return b.width, b.height
+}
+
+func (b *Buffer) SetContent(x, y int, primary rune, combining []rune, style tcell.Style) {
+	if x < 0 || x >= b.width || y < 0 || y >= b.height {
+		return
+	}
+	b.cells[y*b.width+x] = cell{primary, style}
+}
+
+func (b *Buffer) Get(x, y int) (string, tcell.Style, int) {
+	if x < 0 || x >= b.width || y < 0 || y >= b.height {
+		return " ", tcell.StyleDefault, 1
+	}
+	c := b.cells[y*b.width+x]
+	return string(c.r), c.style, 1
+}
+
+// Scaled is a Canvas that draws each of its cells as an n by n block of the
+// Canvas under it, for a fire simulated at a fraction of the screen's
+// resolution. Half blocks are split across the block's rows, so the two
+// halves of a cell keep their shape rather than repeating in stripes.
+type Scaled struct {
+	c         Canvas
+	n         int
+	left, top int
+}
+
+// NewScaled returns a Scaled drawing onto c with its top-left corner at
+// (left, top)
+func NewScaled(c Canvas, n, left, top int) *Scaled {
+	return &Scaled{c: c, n: max(n, 1), left: left, top: top}
+}
+
+func (s *Scaled) SetContent(x, y int, primary rune, combining []rune, style tcell.Style) {
+	fg, bg, _ := style.Decompose()
+	upper, lower := fg, bg
+	if primary == '▄' {
+		upper, lower = bg, fg
+	}
+	split := s.n > 1 && (primary == '▀' || primary == '▄')
+
+	for dy := range s.n {
+		r, st := primary, style
+		if split {
+			// Of the block's 2n half rows, the cell's upper half covers the
+			// first n
+			top, bottom := lower, lower
+			if 2*dy < s.n {
+				top = upper
+			}
+			if 2*dy+1 < s.n {
+				bottom = upper
+			}
+			r, st = '▀', style.Foreground(top).Background(bottom)
+			if top == bottom {
+				r = ' '
+			}
+		}
+		for dx := range s.n {
+			s.c.SetContent(s.left+x*s.n+dx, s.top+y*s.n+dy, r, combining, st)
+		}
+	}
+}
+
+// Get returns the top-left cell of the block drawn for (x, y)
+func (s *Scaled) Get(x, y int) (string, tcell.Style, int) {
+	return s.c.Get(s.left+x*s.n, s.top+y*s.n)
+}
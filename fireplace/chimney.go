@@ -0,0 +1,20 @@
+package fireplace
+
+// How strongly the chimney draws the flames and embers in toward its flue at
+// the very top of the fire. The pull falls away with the square of the
+// distance below it, so the base spreads as usual and only the upper flames
+// taper in.
+const chimneyDraft = 0.5
+
+// flueColumn returns the column the chimney's flue sits over
+func (f *Fire) flueColumn() float64 {
+	return clamp(f.Settings.FlueX, 0, 1) * float64(f.width-1)
+}
+
+// draftAt returns how strongly the chimney pulls at row y, counted from the
+// far edge as updateFire counts them, from 0 at the floor to chimneyDraft at
+// the top
+func (f *Fire) draftAt(y float64) float64 {
+	up := 1 - y/float64(f.fireHeight)
+	return chimneyDraft * up * up
+}
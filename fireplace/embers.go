@@ -0,0 +1,124 @@
+package fireplace
+
+import (
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Most embers a fire keeps alive at once, so a dense shower on a long run
+// can't grow without bound. New ones wait for old ones to go out.
+const maxEmbers = 300
+
+// Least heat a flame needs to throw off an ember
+const emberSpawnHeat = 20
+
+// ember is one spark rising from the flames. Its row is counted from the far
+// edge, as updateFire counts them, so it rises either way a fire burns.
+type ember struct {
+	x, y      float64 // Sub-pixel position
+	age, life int     // Steps since it was thrown off, and how many it glows for
+}
+
+// stepEmbers throws off new embers from the tops of the hottest flames and
+// moves every live one a step further up, dropping those that burned out or
+// left the fire
+func (f *Fire) stepEmbers() {
+	live := f.embers[:0]
+	for _, e := range f.embers {
+		e.age++
+		e.y -= f.Settings.EmberSpeed
+		e.x += (f.rng.Float64() - 0.5) * 0.6 * clamp(f.Settings.Turbulence, 0, 2)
+		if f.Settings.Chimney {
+			// Drawn sideways toward the flue, the more so the higher it rises
+			e.x += (f.flueColumn() - e.x) * f.draftAt(e.y) * 0.2
+		}
+		if f.Settings.Wrap {
+			e.x = math.Mod(e.x+float64(f.width), float64(f.width))
+		}
+		if e.age < e.life && e.y >= 0 && e.x >= 0 && e.x < float64(f.width) {
+			live = append(live, e)
+		}
+	}
+	f.embers = live
+
+	// Whole embers are spawned each step, with the fraction left over
+	// rounded at random to keep the average rate
+	n := int(f.Settings.EmberRate)
+	if f.rng.Float64() < f.Settings.EmberRate-float64(n) {
+		n++
+	}
+	if f.bedLeft > f.bedRight || f.Settings.EmberLife <= 0 {
+		return
+	}
+	for range n {
+		if len(f.embers) >= maxEmbers {
+			return
+		}
+		x := f.bedLeft + f.rng.Intn(f.bedRight-f.bedLeft+1)
+		for y := 0; y < f.fireHeight; y++ {
+			if f.heatAt(x, f.fireRow(y)) >= emberSpawnHeat {
+				// Lifetimes vary a little so a burst doesn't all go out at once
+				life := f.Settings.EmberLife/2 + f.rng.Intn(f.Settings.EmberLife/2+1)
+				f.embers = append(f.embers, ember{x: float64(x), y: float64(y), life: life})
+				break
+			}
+		}
+	}
+}
+
+// drawEmbers lights each ember's sub-pixel, fading down the palette as it
+// ages. Embers only show over open air and flames, never over the wood.
+func (f *Fire) drawEmbers() {
+	for _, e := range f.embers {
+		x := int(e.x)
+		sy := f.fireRow(int(e.y))
+		y := sy / 2
+		if x < 0 || x >= f.width || y < 0 || y >= f.height || f.isWood(x, y) {
+			continue
+		}
+		heat := int(34 * (1 - float64(e.age)/float64(e.life)))
+		if heat < 4 {
+			continue
+		}
+
+		i := y*f.width + x
+		if f.Settings.ASCII {
+			if f.cells[i].r == ' ' {
+				f.setContent(x, y, '\'', tcell.StyleDefault)
+			}
+			continue
+		}
+
+		// Light the ember's half of the cell, whichever block the cell is
+		// drawn with
+		c := f.Settings.Palette[heat]
+		fg, bg, _ := f.cells[i].style.Decompose()
+		if bg == tcell.ColorDefault {
+			bg = tcell.ColorBlack
+		}
+		top, bottom := bg, bg
+		lower := f.Settings.Block == "lower"
+		switch f.cells[i].r {
+		case '▀':
+			top = fg
+		case '▄':
+			bottom = fg
+		case ' ':
+		default:
+			// A glyph ramp's characters and --subcell's eighth blocks have no
+			// halves to light
+			continue
+		}
+		if sy%2 == 0 {
+			top = c
+		} else {
+			bottom = c
+		}
+		if lower {
+			f.setContent(x, y, '▄', tcell.StyleDefault.Foreground(bottom).Background(top))
+		} else {
+			f.setContent(x, y, '▀', tcell.StyleDefault.Foreground(top).Background(bottom))
+		}
+	}
+}
@@ -0,0 +1,572 @@
+// Package fireplace simulates and renders a Doom-style fire burning on a
+// randomly stacked pile of logs. A host application owns the screen: it calls
+// Step to advance the simulation and Render to receive every cell to draw.
+package fireplace
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Settings control how a fire is generated, simulated and drawn. They can be
+// changed between calls to Step and Render, except for Layout, HearthWidth,
+// Logs, NoLogs, Mask, LogSpacing, CoreLogs, CoreSpread, NoFlatten and Down,
+// which only take effect in NewFire.
+type Settings struct {
+	Palette     []tcell.Color // 37 heat colors, from cold (0) to hottest (36)
+	Layout      string        // How logs are arranged: "hearth" or "teepee"
+	HearthWidth int           // Columns the hearth spans (0 = whole fire)
+	Logs        int           // Fixed number of logs, up to MaxLogs (0 = scale with width)
+	NoLogs      bool          // Burn from a hidden strip of fuel on the floor instead of logs
+	Mask        image.Image   // Burn a shape instead of logs: the image's dark pixels, scaled to fit (nil = logs)
+	LogSpacing  float64       // Scale of the room hearth logs leave around each other (1 = standard)
+	CoreLogs    int           // Hearth logs placed first, close to the middle, to anchor the bed
+	CoreSpread  float64       // Columns either side of the middle the core logs are kept within
+	NoFlatten   bool          // Leave hearth logs with nothing under them at their random angles
+	Down        bool          // Burn downward from logs on the ceiling
+	FireSpan    float64       // Fraction of the log span that gets refueled
+	FuelProfile string        // How refueling thins out across the span, one of FuelProfiles ("" = linear)
+	LickChance  float64       // Chance per cell of a flame lick carrying higher
+	Turbulence  float64       // Sideways drift and licks, from 0 (laminar) through 1 (standard) to 2 (wild)
+	Convection  float64       // How strongly flames lean toward the hotter side below them, from 0 (not at all) to 1
+	EdgeFalloff float64       // How sharply flames die away toward the hearth's sides (6 = standard, 0 = not at all)
+	FlameHeight float64       // Scale of how far the flames reach above the wood (1 = standard)
+	Wrap        bool          // Let flames drifting off one side come back in on the other
+	Chimney     bool          // Draw the upper flames and embers in toward a flue above the fire
+	FlueX       float64       // Where the flue is across the fire, from 0 (left edge) to 1 (right edge)
+	Wind        float64       // Sideways push on the flame tips in cells per sub-pixel row, negative to the left (0 = still air)
+	NoTopClear  bool          // Let flames reach the top row, where stray heat can then hang
+	Haze        bool          // Waver the air just above the flames as if seen through rising heat
+	HeatSources int           // Heat injections per refueled column each step
+	EmberRate   float64       // Embers thrown off by the flames per step, on average (0 = none)
+	EmberSpeed  float64       // Sub-pixel rows an ember rises each step
+	EmberLife   int           // Most steps an ember glows for before it goes out
+	RefuelDepth float64       // Fraction of the wood's height, from the floor up, that heat is injected into
+	MaxHeat     int           // Heat injected by refueling, at most 36
+	BurnLevel   float64       // How much fuel the fire still gets, from 1 (full) down to 0 (out)
+	ASCII       bool          // Draw with plain characters and no color
+	Ambient     bool          // Light the background with a dim gradient
+	Smooth      bool          // Blur heat vertically when drawing
+	Dither      bool          // Ordered-dither the fire's colors
+	Floor       int           // Least heat shown over the log bed (0 = off)
+	FloorLine   bool          // Draw a row of ash along the floor, warmed by the fire above
+	Flare       int           // Extra heat added to visible flames when drawing
+	BlendCap    float64       // Most a flame covers what's behind it, from above 0 to 1 (0.85 = standard)
+	Consume     bool          // Burn the logs away under the flames over time
+	GlyphRamp   string        // Characters for increasing heat to draw flames with ("" = half blocks)
+	Subcell     bool          // Draw flame tips with eighth blocks, filled to match their heat
+	Block       string        // Half block to draw flames with: "upper" ('▀', the default) or "lower" ('▄')
+	Coals       bool          // Keep only a low, pulsing bed of coals with no tall flames
+	Breathe     float64       // How far the fire subsides between slow swells, from 0 (steady) to 1
+	Texture     float64       // Bark texture, from 0 (smooth) through 1 (standard) to 2 (gnarled)
+	WoodColor   string        // Tint of the logs, one of WoodColors ("" = oak)
+	WoodLight   float64       // Brightness of the logs before the fire's glow (1 = standard)
+	LogGlow     [3]float64    // How much a log's red, green and blue rise per unit of heat around it
+	Vignette    float64       // How much the frame darkens towards its corners, from 0 (none) to 1
+}
+
+// DefaultSettings returns the settings of a fire built with no options
+func DefaultSettings() Settings {
+	return Settings{
+		Palette:     NewPalette(DoomPalette),
+		Layout:      "hearth",
+		LogSpacing:  1,
+		CoreLogs:    4,
+		CoreSpread:  5,
+		FireSpan:    0.8,
+		LickChance:  0.2,
+		Turbulence:  1,
+		EdgeFalloff: 6,
+		FlameHeight: 1,
+		FlueX:       0.5,
+		HeatSources: 3,
+		EmberSpeed:  0.5,
+		EmberLife:   40,
+		RefuelDepth: 0.75,
+		MaxHeat:     36,
+		BurnLevel:   1,
+		BlendCap:    0.85,
+		Texture:     1,
+		WoodLight:   1,
+		LogGlow:     [3]float64{5, 2, 0},
+	}
+}
+
+// Option configures a new fire
+type Option func(*Fire)
+
+// WithSettings replaces all of a new fire's settings
+func WithSettings(s Settings) Option {
+	return func(f *Fire) { f.Settings = s }
+}
+
+// WithPalette draws the fire with the given 37 heat colors
+func WithPalette(p []tcell.Color) Option {
+	return func(f *Fire) { f.Settings.Palette = p }
+}
+
+// WithRand makes the fire draw all of its randomness, for both the logs and
+// the flames, from rng. A fire given a generator with a fixed seed plays
+// out the same way every time.
+func WithRand(rng *rand.Rand) Option {
+	return func(f *Fire) { f.rng = rng }
+}
+
+// WithLogs builds the fire on the given logs, as Logs reports them, instead
+// of generating new ones. They're drawn in the order given, so a fire's own
+// logs come back exactly as they were.
+func WithLogs(logs []Log) Option {
+	return func(f *Fire) { f.givenLogs = logs }
+}
+
+// Fire is one fire simulation with its own logs, w cells wide and h tall
+type Fire struct {
+	Settings Settings
+
+	width       int
+	height      int         // Simulation region height
+	fireHeight  int         // Simulation height (height * 2 + seed)
+	hearthLeft  int         // Left boundary of the fireplace
+	hearthRight int         // Right boundary of the fireplace
+	offset      int         // Columns the hearth has been moved right by MoveHearth
+	down        bool        // Settings.Down as the logs were generated
+	noLogs      bool        // Settings.NoLogs as the logs were generated
+	mask        image.Image // Settings.Mask as the logs were generated
+	fire        []int
+	woodMap     []int // Visible wood: the log ID for each cell (0 = empty)
+	fuelMap     []int // Where refueling injects heat; woodMap unless there are no logs
+	logHeights  []int // Cached fuel height per column, rebuilt with fuelMap
+	bedLeft     int   // Leftmost column with wood (width if there is none)
+	bedRight    int   // Rightmost column with wood
+	logCount    int   // Number of logs generated
+	logs        []Log // Logs from the last generation, sorted by depth
+	givenLogs   []Log // Logs from WithLogs to use instead of generating (nil = generate)
+	cells       []cell
+	tips        []int // Ring of each column's recent flame tops for Haze, hazeSteps a column
+	tipAt       int   // Slot in tips the next flame tops go in
+	hazeCells   []cell
+	embers      []ember    // Live embers, at most maxEmbers
+	rng         *rand.Rand // Source of all randomness in generation and simulation
+	steps       int        // Simulation steps taken so far
+}
+
+// NewFire creates a w by h fire with freshly generated logs. The fire grid
+// starts cold; call Step a few dozen times before the first Render for a fire
+// that's already burning.
+func NewFire(w, h int, opts ...Option) *Fire {
+	f := &Fire{Settings: DefaultSettings(), width: w, height: h}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.rng == nil {
+		f.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	f.down = f.Settings.Down
+	f.noLogs = f.Settings.NoLogs
+	f.mask = f.Settings.Mask
+
+	// Hearth fills the entire region unless a preset narrows it
+	f.hearthLeft = 0
+	f.hearthRight = w
+	if hw := f.Settings.HearthWidth; hw > 0 && hw < w {
+		f.hearthLeft = (w - hw) / 2
+		f.hearthRight = f.hearthLeft + hw
+	}
+
+	// Fire simulation grid
+	f.fireHeight = h * 2
+	f.initFire()
+	f.generateLogs()
+	f.cells = make([]cell, w*h)
+	return f
+}
+
+// Size returns the width and height of the fire in cells
+func (f *Fire) Size() (int, int) {
+	return f.width, f.height
+}
+
+// Step advances the simulation by one tick
+func (f *Fire) Step() {
+	f.updateFire()
+	if f.Settings.EmberRate > 0 || len(f.embers) > 0 {
+		f.stepEmbers()
+	}
+	if f.Settings.Consume {
+		f.consumeLogs()
+	}
+}
+
+// MoveHearth slides the hearth and its logs dx columns to the right (left
+// for a negative dx), stopping where the wood would leave the fire's area.
+// The flames already burning are left to drift after it. Logs keeps
+// reporting where the logs were generated.
+func (f *Fire) MoveHearth(dx int) {
+	if f.bedLeft <= f.bedRight {
+		dx = clamp(dx, -f.bedLeft, f.width-1-f.bedRight)
+	}
+	if dx == 0 {
+		return
+	}
+
+	f.offset += dx
+	f.hearthLeft += dx
+	f.hearthRight += dx
+	f.rasterize()
+}
+
+// Heat returns the total heat in the fire grid
+func (f *Fire) Heat() int {
+	total := 0
+	for _, h := range f.fire {
+		total += h
+	}
+	return total
+}
+
+// Glow returns the color of the fire's light: the average color of its
+// visible flames, or the coldest palette color when none are burning
+func (f *Fire) Glow() tcell.Color {
+	var r, g, b, n int32
+	for _, h := range f.fire {
+		if h < 4 {
+			continue
+		}
+		cr, cg, cb := f.Settings.Palette[clamp(h, 0, 36)].RGB()
+		r, g, b, n = r+cr, g+cg, b+cb, n+1
+	}
+	if n == 0 {
+		return f.Settings.Palette[0]
+	}
+	return tcell.NewRGBColor(r/n, g/n, b/n)
+}
+
+// Logs returns the generated logs, sorted from the back of the pile to the
+// front
+func (f *Fire) Logs() []Log {
+	return f.logs
+}
+
+func (f *Fire) initFire() {
+	f.fire = make([]int, f.width*f.fireHeight)
+}
+
+// Sub-pixel rows coals glow above the top of the wood in their column
+const coalRise = 3
+
+// Steps in one swell and subsidence of a breathing fire (8s at 20 steps a
+// second)
+const breathSteps = 160
+
+func (f *Fire) updateFire() {
+	f.steps++
+	center := float64(f.hearthLeft+f.hearthRight) / 2.0
+	halfWidth := float64(f.hearthRight-f.hearthLeft) / 2.0
+	turbulence := clamp(f.Settings.Turbulence, 0, 2)
+	flameHeight := f.Settings.FlameHeight
+
+	// Rows below are counted from the far edge the flames burn toward, and
+	// fireRow maps them onto the grid, so burning down mirrors burning up
+
+	// Clear the top row of fire to prevent "hanging" artifacts. Heat that
+	// drifts sideways out of a cell leaves it unwritten, so without the
+	// clear a top-row cell can keep its heat until something lands on it.
+	if !f.Settings.NoTopClear {
+		for x := 0; x < f.width; x++ {
+			f.setHeat(x, f.fireRow(0), 0)
+		}
+	}
+
+	// 1. Propagate and decay
+	for x := 0; x < f.width; x++ {
+		for y := 1; y < f.fireHeight; y++ {
+			pixel := f.heatAt(x, f.fireRow(y))
+
+			if pixel == 0 {
+				f.setHeat(x, f.fireRow(y-1), 0)
+			} else {
+				// Calmer flames drift less often and wilder ones drift further
+				drift := f.rng.Intn(3) - 1
+				// Convection draws the heat in toward the hot core: the
+				// hotter one neighbor is than the other, the likelier the
+				// drift goes its way
+				if f.Settings.Convection > 0 {
+					row := f.fireRow(y)
+					lean := f.Settings.Convection * float64(f.heatAt(x+1, row)-f.heatAt(x-1, row)) / 36
+					if f.rng.Float64() < math.Abs(lean) {
+						drift = 1
+						if lean < 0 {
+							drift = -1
+						}
+					}
+				}
+				if turbulence < 1 && drift != 0 && f.rng.Float64() >= turbulence {
+					drift = 0
+				} else if turbulence > 1 && f.rng.Float64() < turbulence-1 {
+					drift *= 2
+				}
+				// Wind bends the flames, hardly at all at the base and
+				// most at the tips. A fractional push is rounded at random
+				// to keep its average.
+				if f.Settings.Wind != 0 {
+					push := math.Abs(f.Settings.Wind) * (1 - float64(y)/float64(f.fireHeight))
+					n := int(push)
+					if f.rng.Float64() < push-float64(n) {
+						n++
+					}
+					if f.Settings.Wind < 0 {
+						n = -n
+					}
+					drift += n
+				}
+				// A chimney draws the flames in toward its flue, hardly
+				// at all low down and most strongly near the top
+				if f.Settings.Chimney {
+					flue := f.flueColumn()
+					if math.Abs(flue-float64(x)) >= 1 && f.rng.Float64() < f.draftAt(float64(y)) {
+						if flue < float64(x) {
+							drift--
+						} else {
+							drift++
+						}
+					}
+				}
+				// Drift off one side either piles up against it or,
+				// wrapping, comes back in on the other
+				dstX := x + drift
+				if f.Settings.Wrap {
+					dstX = ((dstX % f.width) + f.width) % f.width
+				} else if dstX < 0 {
+					dstX = 0
+				} else if dstX >= f.width {
+					dstX = f.width - 1
+				}
+
+				dist := math.Abs(float64(x) - center)
+				normDist := dist / (halfWidth * 0.8) // Reverted to previous width
+
+				// Slower decay for a larger, taller fire, and faster toward
+				// the sides. A low falloff tapers the edges out gently
+				// where the standard one cuts them off.
+				decay := 1 + int(normDist*normDist*f.Settings.EdgeFalloff)
+
+				if f.Settings.Coals {
+					// No licks, and nothing rises far above the wood
+					decay += 1
+				} else if y < f.fireHeight/2 { // Heat carries further up
+					// Occasionally reduce decay to let "licks" of flame go higher
+					if f.rng.Float64() > 1-f.Settings.LickChance*turbulence {
+						decay = 0
+					} else {
+						decay += 1
+					}
+				}
+
+				// Taller flames lose heat more slowly as they rise. Heat
+				// is whole, so the scaled decay is rounded at random to
+				// keep its average.
+				if flameHeight > 0 && flameHeight != 1 && decay > 0 {
+					scaled := float64(decay) / flameHeight
+					decay = int(scaled)
+					if f.rng.Float64() < scaled-float64(decay) {
+						decay++
+					}
+				}
+
+				newHeat := max(pixel-decay, 0)
+				if f.Settings.Coals && f.fireHeight-y > f.getLogHeight(dstX)*2+coalRise {
+					newHeat = 0
+				}
+				f.setHeat(dstX, f.fireRow(y-1), newHeat)
+			}
+		}
+	}
+
+	// 2. Stable Refuel
+	fireCenter, halfSpan := f.refuelSpan()
+
+	// A breathing fire gets less fuel as it subsides
+	burn := f.Settings.BurnLevel
+	if f.Settings.Breathe > 0 {
+		swell := (1 + math.Sin(2*math.Pi*float64(f.steps)/breathSteps)) / 2
+		burn *= 1 - clamp(f.Settings.Breathe, 0, 1)*(1-swell)
+	}
+
+	// Coals burn hot and breathe slowly instead of flaring
+	heat := float64(f.Settings.MaxHeat) * burn
+	if f.Settings.Coals {
+		heat *= 0.9 + 0.1*math.Sin(float64(f.steps)*0.05)
+	}
+
+	for x := 0; x < f.width; x++ {
+		h := f.getLogHeight(x)
+		if h <= 0 {
+			continue
+		}
+
+		dist := math.Abs(float64(x) - fireCenter)
+		normDist := dist / halfSpan
+		if f.mask != nil {
+			// A drawn shape burns evenly all over, out to its edges
+			normDist = 0
+		} else if dist > halfSpan {
+			// Only refuel within the 80% span
+			continue
+		}
+
+		// A fire burning down refuels fewer columns
+		if burn < 1 && f.rng.Float64() > burn {
+			continue
+		}
+
+		if f.rng.Float64() > fuelFalloff(f.Settings.FuelProfile, normDist) {
+			// Inject heat at various depths within logs
+			for range f.Settings.HeatSources { // More heat sources
+				// Fire extends higher into the bundle. A drawn shape needn't
+				// reach down to the floor, so it only burns where it is.
+				reach := int(float64(h)*clamp(f.Settings.RefuelDepth, 0, 1)) + 1
+				if f.mask != nil {
+					reach = h + 1
+				}
+				d := f.rng.Intn(reach)
+				fireY := (f.height - 1 - d) * 2
+				if f.mask != nil && !f.isFuel(x, f.fireRow(fireY)/2) {
+					continue
+				}
+				if fireY >= 0 && fireY < f.fireHeight {
+					f.setHeat(x, f.fireRow(fireY), int(heat))
+				}
+			}
+		}
+	}
+
+	if f.Settings.Haze {
+		f.recordTips()
+	}
+}
+
+// FuelProfiles names the shapes Settings.FuelProfile can give the fire:
+// "linear" thins the flames steadily toward the sides, "gaussian" gathers
+// them into a tall middle with tapering sides and "flat" burns an even wall
+// across the whole span
+var FuelProfiles = []string{"linear", "gaussian", "flat"}
+
+// Width of the gaussian profile, as a fraction of the half span
+const gaussianWidth = 0.4
+
+// fuelFalloff returns the chance a column refueling could reach is passed
+// over this tick, for a column normDist of the half span out from the
+// middle
+func fuelFalloff(profile string, normDist float64) float64 {
+	switch profile {
+	case "gaussian":
+		return 1 - math.Exp(-normDist*normDist/(2*gaussianWidth*gaussianWidth))
+	case "flat":
+		return 0
+	}
+	return normDist * 0.9
+}
+
+// refuelSpan returns the column refueling centers on and how far either side
+// of it columns are refueled, unless a mask burns its whole shape
+func (f *Fire) refuelSpan() (center, half float64) {
+	logSpan := float64(f.bedRight - f.bedLeft)
+	return float64(f.bedLeft+f.bedRight) / 2.0, logSpan * f.Settings.FireSpan / 2.0
+}
+
+// fireRow maps a sub-pixel row counted from the top, as updateFire sees it,
+// to its row in the fire grid
+func (f *Fire) fireRow(y int) int {
+	if f.down {
+		return f.fireHeight - 1 - y
+	}
+	return y
+}
+
+// Returns the height of the fuel from the bottom at column x
+func (f *Fire) getLogHeight(x int) int {
+	if x < 0 || x >= len(f.logHeights) {
+		return 0
+	}
+	return f.logHeights[x]
+}
+
+// scanLogHeight measures the fuel height at column x directly from fuelMap
+func (f *Fire) scanLogHeight(x int) int {
+	if x < 0 || x >= f.width {
+		return 0
+	}
+	// Logs on the ceiling reach down from the top
+	if f.down {
+		for y := f.height - 1; y >= 0; y-- {
+			if f.isFuel(x, y) {
+				return y
+			}
+		}
+		return 0
+	}
+
+	// Scan from top (0) to bottom (height-1)
+	for y := 0; y < f.height; y++ {
+		if f.isFuel(x, y) {
+			// Found top of fuel
+			return f.height - 1 - y
+		}
+	}
+	return 0
+}
+
+func (f *Fire) isWood(x, y int) bool {
+	return f.woodAt(x, y) != 0
+}
+
+func (f *Fire) isFuel(x, y int) bool {
+	i := y*f.width + x
+	if x < 0 || x >= f.width || y < 0 || i >= len(f.fuelMap) {
+		return false
+	}
+	return f.fuelMap[i] != 0
+}
+
+// The grids are only indexed through these accessors, which treat anything
+// outside the grid as empty rather than risk a panic on a stray coordinate
+
+// heatAt returns the heat at column x of sub-pixel row sy
+func (f *Fire) heatAt(x, sy int) int {
+	i := sy*f.width + x
+	if x < 0 || x >= f.width || sy < 0 || i >= len(f.fire) {
+		return 0
+	}
+	return f.fire[i]
+}
+
+// setHeat sets the heat at column x of sub-pixel row sy
+func (f *Fire) setHeat(x, sy, heat int) {
+	i := sy*f.width + x
+	if x < 0 || x >= f.width || sy < 0 || i >= len(f.fire) {
+		return
+	}
+	f.fire[i] = heat
+}
+
+// woodIDs returns the highest ID woodMap can hold: one for each log, and a
+// mask's wood is painted as ID 1 even with no logs
+func (f *Fire) woodIDs() int {
+	if f.mask != nil {
+		return max(f.logCount, 1)
+	}
+	return f.logCount
+}
+
+// woodAt returns the ID of the log covering cell (x, y), or 0 for none
+func (f *Fire) woodAt(x, y int) int {
+	i := y*f.width + x
+	if x < 0 || x >= f.width || y < 0 || i >= len(f.woodMap) {
+		return 0
+	}
+	return f.woodMap[i]
+}
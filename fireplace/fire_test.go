@@ -0,0 +1,122 @@
+package fireplace
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// newTestFire builds a w by h fire drawing on a source seeded with seed, with
+// change made to the default settings first unless it's nil
+func newTestFire(w, h int, seed int64, change func(*Settings), opts ...Option) *Fire {
+	settings := DefaultSettings()
+	if change != nil {
+		change(&settings)
+	}
+	opts = append([]Option{WithSettings(settings), WithRand(rand.New(rand.NewSource(seed)))}, opts...)
+	return NewFire(w, h, opts...)
+}
+
+// checkInvariants reports the first way the fire grid breaks the
+// simulation's invariants, or nil if it holds them all. It's meant to be
+// called after each Step while the settings stay the same, to catch a change
+// to the simulation that lets heat run away or appear where it can't come
+// from.
+//
+//   - No cell is colder than 0 or hotter than refueling ever injects.
+//   - The deepest sub-pixel row is never written, so it stays at 0.
+//   - The row above it is cleared by propagation each step, so any heat in
+//     it was injected by this step's refuel: over fuel, and within the
+//     refuel span unless a mask burns its whole shape.
+func checkInvariants(f *Fire) error {
+	if len(f.fire) != f.width*f.fireHeight {
+		return fmt.Errorf("fire grid has %d cells, want %dx%d", len(f.fire), f.width, f.fireHeight)
+	}
+
+	limit := max(f.Settings.MaxHeat, int(float64(f.Settings.MaxHeat)*f.Settings.BurnLevel))
+	for i, heat := range f.fire {
+		if heat < 0 || heat > limit {
+			return fmt.Errorf("cell (%d, %d) has heat %d, outside 0 to %d", i%f.width, i/f.width, heat, limit)
+		}
+	}
+
+	deepest := f.fireRow(f.fireHeight - 1)
+	refueled := f.fireRow(f.fireHeight - 2)
+	center, half := f.refuelSpan()
+	for x := range f.width {
+		if heat := f.heatAt(x, deepest); heat != 0 {
+			return fmt.Errorf("cell (%d, %d) in the deepest row has heat %d", x, deepest, heat)
+		}
+
+		if f.heatAt(x, refueled) == 0 {
+			continue
+		}
+		if f.getLogHeight(x) <= 0 {
+			return fmt.Errorf("column %d was refueled with no fuel under it", x)
+		}
+		if f.mask == nil && math.Abs(float64(x)-center) > half {
+			return fmt.Errorf("column %d was refueled outside the span %g to %g", x, center-half, center+half)
+		}
+	}
+	return nil
+}
+
+// Steps each invariant test runs for, long enough for the fire to settle
+// into its usual height and for a thrown-on log to burn in
+const invariantSteps = 400
+
+func TestInvariants(t *testing.T) {
+	tests := []struct {
+		name   string
+		w, h   int
+		change func(*Settings)
+	}{
+		{"smallest", 4, 4, nil},
+		{"odd", 13, 9, nil},
+		{"standard", 80, 24, nil},
+		{"wide", 400, 25, nil},
+		{"tall", 40, 120, nil},
+		{"teepee", 80, 24, func(s *Settings) { s.Layout = "teepee" }},
+		{"down", 80, 24, func(s *Settings) { s.Down = true }},
+		{"no logs", 80, 24, func(s *Settings) { s.NoLogs = true }},
+		{"wild and windy", 80, 24, func(s *Settings) { s.Turbulence, s.Wind, s.Wrap = 2, 1.5, true }},
+		{"convection and chimney", 80, 24, func(s *Settings) { s.Convection, s.Chimney, s.FlueX = 1, true, 0.2 }},
+		{"burning down", 80, 24, func(s *Settings) { s.BurnLevel = 0.3 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFire(tt.w, tt.h, 1, tt.change)
+
+			for i := range invariantSteps {
+				// A log thrown on partway flares up the heat around it
+				if i == invariantSteps/2 {
+					f.AddLog()
+				}
+				f.Step()
+				if err := checkInvariants(f); err != nil {
+					t.Fatalf("step %d: %v", i+1, err)
+				}
+				// A fire that still has fuel must never go completely cold
+				if i >= 60 && f.Heat() == 0 && !f.BurnedOut() && f.Settings.BurnLevel > 0 {
+					t.Fatalf("step %d: the fire went out with fuel left", i+1)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkStep times one simulation step of a fire that's already burning,
+// with the allocations it makes: a step at a steady size shouldn't need any
+func BenchmarkStep(b *testing.B) {
+	f := newTestFire(80, 24, 1, nil)
+	for range 60 {
+		f.Step()
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		f.Step()
+	}
+}
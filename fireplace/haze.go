@@ -0,0 +1,77 @@
+package fireplace
+
+// Steps of flame-top history kept for Haze, which is also how many rows
+// above the flames the shimmer reaches
+const hazeSteps = 6
+
+// recordTips notes the row, counted from the far edge, of the highest
+// visible flame in each column, or fireHeight for a column without one
+func (f *Fire) recordTips() {
+	if len(f.tips) != f.width*hazeSteps {
+		f.tips = make([]int, f.width*hazeSteps)
+		for i := range f.tips {
+			f.tips[i] = f.fireHeight
+		}
+	}
+
+	for x := 0; x < f.width; x++ {
+		tip := f.fireHeight
+		for y := 0; y < f.fireHeight; y++ {
+			if f.heatAt(x, f.fireRow(y)) >= 4 {
+				tip = y
+				break
+			}
+		}
+		f.tips[x*hazeSteps+f.tipAt] = tip
+	}
+	f.tipAt = (f.tipAt + 1) % hazeSteps
+}
+
+// drawHaze shifts the cells just above each column's flames a column either
+// way. Air further up left the flames earlier, so each row takes its shift
+// from an older flame top: how far that stood from the column's recent
+// average. It displaces whatever was drawn there, flames and background
+// alike, except the wood, so it must come after everything else.
+func (f *Fire) drawHaze() {
+	if len(f.tips) != f.width*hazeSteps {
+		return
+	}
+	f.hazeCells = append(f.hazeCells[:0], f.cells...)
+
+	for x := 0; x < f.width; x++ {
+		history := f.tips[x*hazeSteps : (x+1)*hazeSteps]
+		latest := history[(f.tipAt+hazeSteps-1)%hazeSteps]
+		if latest >= f.fireHeight {
+			continue
+		}
+		mean := 0.0
+		for _, tip := range history {
+			mean += float64(tip)
+		}
+		mean /= hazeSteps
+
+		for d := 1; d <= hazeSteps; d++ {
+			row := latest/2 - d
+			if row < 0 {
+				break
+			}
+			dev := float64(history[(f.tipAt+hazeSteps-d)%hazeSteps]) - mean
+			shift := 0
+			if dev > 1 {
+				shift = 1
+			} else if dev < -1 {
+				shift = -1
+			}
+			src := x + shift
+			if shift == 0 || src < 0 || src >= f.width {
+				continue
+			}
+			// Wood stays put, and the air over it doesn't take its bark
+			y := f.fireRow(row*2) / 2
+			if f.woodMap[y*f.width+x] != 0 || f.woodMap[y*f.width+src] != 0 {
+				continue
+			}
+			f.cells[y*f.width+x] = f.hazeCells[y*f.width+src]
+		}
+	}
+}
@@ -0,0 +1,502 @@
+package fireplace
+
+import (
+	"math"
+	"sort"
+)
+
+// Log is a single stick of wood. Positions are in cells, with y growing down.
+type Log struct {
+	ID             int     // Position in the draw order, from 1 (furthest back)
+	MidX, MidY     float64 // Center of the stick
+	Angle          float64 // Tilt from horizontal, in radians
+	Length         float64 // End to end, in aspect-corrected cells
+	R              float64 // Radius
+	dx, dy         float64
+	depth          float64
+	x1, y1, x2, y2 float64
+	burnRate       float64 // Fraction of the log burned per step in full heat
+	burned         float64 // Fraction burned away so far, up to 1 (gone)
+}
+
+// NewLog returns a stick of radius r whose axis runs from (x1, y1) to
+// (x2, y2), for building a fire with WithLogs
+func NewLog(x1, y1, x2, y2, r float64) Log {
+	dx, dy := (x2-x1)/2, (y2-y1)/2
+	return Log{
+		MidX: x1 + dx, MidY: y1 + dy,
+		Angle: math.Atan2(dy*aspect, dx), Length: 2 * math.Hypot(dx, dy*aspect), R: r,
+		dx: dx, dy: dy,
+		x1: x1, y1: y1, x2: x2, y2: y2,
+	}
+}
+
+// Ends returns the two ends of the log's axis, as NewLog takes them
+func (l Log) Ends() (x1, y1, x2, y2 float64) {
+	return l.x1, l.y1, l.x2, l.y2
+}
+
+// Burned returns how much of the log has burned away with Settings.Consume,
+// from 0 up to 1 (gone)
+func (l Log) Burned() float64 {
+	return l.burned
+}
+
+// Upper bound on Settings.Logs, since rasterizing is O(logs) per cell
+const MaxLogs = 400
+
+// Terminal cells are roughly twice as tall as they are wide
+const aspect = 2.0
+
+// Steps a log takes to burn away in full heat, on average, with --consume
+const burnSteps = 3600
+
+func (f *Fire) generateLogs() {
+	// Sticks should be thin
+	baseRadius := float64(f.height) / 90.0
+	if baseRadius < 0.4 {
+		baseRadius = 0.4
+	}
+
+	var tempLogs []Log
+	switch {
+	case f.givenLogs != nil:
+		// Already in place and in order, so there's nothing to sort or flip
+		tempLogs = append(tempLogs, f.givenLogs...)
+		for i := range tempLogs {
+			tempLogs[i].depth = float64(i)
+		}
+	case f.noLogs, f.mask != nil:
+		// rasterize lays down the fuel strip or the mask on its own
+	case f.Settings.Layout == "teepee":
+		tempLogs = f.teepeeLogs(baseRadius)
+	default:
+		tempLogs = f.hearthLogs(baseRadius)
+	}
+
+	// Sort logs by depth
+	sort.Slice(tempLogs, func(i, j int) bool {
+		return tempLogs[i].depth < tempLogs[j].depth
+	})
+
+	f.logCount = len(tempLogs)
+	for i := range tempLogs {
+		tempLogs[i].ID = i + 1
+		tempLogs[i].burnRate = (0.5 + f.rng.Float64()) / burnSteps
+		if f.down && f.givenLogs == nil {
+			f.flipLog(&tempLogs[i])
+		}
+	}
+	f.logs = tempLogs
+	f.rasterize()
+}
+
+// rasterize draws the logs into woodMap where the hearth now sits, shrinking
+// partly burned logs toward their centers and leaving out burned-away ones.
+// fuelMap is rebuilt to match.
+func (f *Fire) rasterize() {
+	f.woodMap = make([]int, f.width*f.height)
+
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			for i := len(f.logs) - 1; i >= 0; i-- {
+				if f.covers(&f.logs[i], x, y) {
+					f.woodMap[y*f.width+x] = f.logs[i].ID
+					break
+				}
+			}
+		}
+	}
+
+	if f.mask != nil {
+		f.paintMask()
+	}
+
+	// The logs are the fuel, unless there are none to show
+	f.fuelMap = f.woodMap
+	if f.noLogs {
+		f.fuelMap = f.fuelStrip()
+	}
+	f.measureFuel()
+}
+
+// covers reports whether log l, as far as it has burned, covers cell (x, y)
+func (f *Fire) covers(l *Log, x, y int) bool {
+	if l.burned >= 1 {
+		return false
+	}
+	scale := 1 - l.burned
+	mx, my := (l.x1+l.x2)/2, (l.y1+l.y2)/2
+	r := l.R * scale
+
+	px, py := float64(x-f.offset), float64(y)*aspect
+	ax, ay := mx+(l.x1-mx)*scale, (my+(l.y1-my)*scale)*aspect
+	bx, by := mx+(l.x2-mx)*scale, (my+(l.y2-my)*scale)*aspect
+
+	abx, aby := bx-ax, by-ay
+	apx, apy := px-ax, py-ay
+	lenSq := abx*abx + aby*aby
+	if lenSq == 0 {
+		return false
+	}
+	t := (apx*abx + apy*aby) / lenSq
+	if t < 0 {
+		t = 0
+	} else if t > 1 {
+		t = 1
+	}
+
+	cx, cy := ax+t*abx, ay+t*aby
+	dx, dy := px-cx, py-cy
+	return dx*dx+dy*dy <= (r*aspect)*(r*aspect)
+}
+
+// measureFuel caches the fuel height of every column and the bed's extent.
+// fuelMap only changes through rasterize and AddLog, which both call it.
+func (f *Fire) measureFuel() {
+	f.logHeights = make([]int, f.width)
+	f.bedLeft, f.bedRight = f.width, 0
+	for x := range f.logHeights {
+		f.logHeights[x] = f.scanLogHeight(x)
+		if f.logHeights[x] > 0 {
+			f.bedLeft = min(f.bedLeft, x)
+			f.bedRight = max(f.bedRight, x)
+		}
+	}
+}
+
+// AddLog throws one more stick onto the pile, resting on whatever wood is
+// already where it lands and in front of the rest, and flares the flames up
+// around it. It reports false without adding anything once there are
+// MaxLogs logs.
+func (f *Fire) AddLog() bool {
+	if len(f.logs) >= MaxLogs {
+		return false
+	}
+
+	baseRadius := math.Max(float64(f.height)/90.0, 0.4)
+	centerX := float64(f.hearthLeft+f.hearthRight)/2.0 - float64(f.offset)
+	bottomY := float64(f.height - 1)
+
+	r := baseRadius * (0.6 + f.rng.Float64()*0.8)
+	length := 7.0 + f.rng.Float64()*12.0
+	angle := (f.rng.Float64() - 0.5) * math.Pi * 0.2
+	dx := math.Cos(angle) * length / 2.0
+	dy := math.Sin(angle) * length / 2.0 / aspect
+
+	// Land near the middle, clear of the sides
+	mx := centerX + f.rng.NormFloat64()*float64(f.width)*0.15
+	mx = clamp(mx, math.Abs(dx)+r, float64(f.width-1)-math.Abs(dx)-r)
+
+	// Settle on top of the wood below, sinking into it a little. The log
+	// is flipped after, so this works out the same burning down.
+	top := float64(f.getLogHeight(int(mx) + f.offset))
+	my := math.Max(bottomY-top-r*0.5, math.Abs(dy)+r)
+
+	l := Log{
+		ID: len(f.logs) + 1, MidX: mx, MidY: my,
+		Angle: angle, Length: length, R: r,
+		x1: mx - dx, y1: my - dy, x2: mx + dx, y2: my + dy,
+		burnRate: (0.5 + f.rng.Float64()) / burnSteps,
+	}
+	if f.down {
+		f.flipLog(&l)
+	}
+	f.logs = append(f.logs, l)
+	f.logCount = len(f.logs)
+
+	// The new log is in front, so it only ever paints over the others. The
+	// flare leaves out the deepest sub-pixel row, which nothing clears, so
+	// heat put there would hang in it for good.
+	deepest := f.fireRow(f.fireHeight - 1)
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			if f.covers(&l, x, y) {
+				f.woodMap[y*f.width+x] = l.ID
+				for _, sy := range []int{y * 2, y*2 + 1} {
+					if sy != deepest {
+						f.setHeat(x, sy, f.Settings.MaxHeat)
+					}
+				}
+			}
+		}
+	}
+	f.measureFuel()
+	return true
+}
+
+// Number of size steps a log shrinks through as it burns; woodMap is only
+// rebuilt when a log crosses one
+const burnStages = 10
+
+// Heat every log burns at, at least, with --consume
+const smolderHeat = 6
+
+// consumeLogs burns the logs down by the heat at their centers, and rebuilds
+// woodMap when any has visibly shrunk
+func (f *Fire) consumeLogs() {
+	changed := false
+	for i := range f.logs {
+		l := &f.logs[i]
+		if l.burned >= 1 {
+			continue
+		}
+
+		// The log burns from the flames where it's drawn, moved along with the
+		// hearth
+		x := clamp(int((l.x1+l.x2)/2)+f.offset, 0, f.width-1)
+		sy := clamp(int((l.y1+l.y2)/2)*2, 0, f.fireHeight-1)
+
+		// Logs away from the flames still smolder, so the bed burns out
+		// completely in the end
+		heat := max(f.heatAt(x, sy), smolderHeat)
+
+		before := l.burned
+		l.burned = math.Min(l.burned+l.burnRate*float64(heat)/36, 1)
+		if int(l.burned*burnStages) != int(before*burnStages) {
+			changed = true
+		}
+	}
+
+	if changed {
+		f.rasterize()
+	}
+}
+
+// Rows of fuel along the floor with NoLogs
+const fuelStripRows = 3
+
+// fuelStrip builds a fuel map with a flat strip along the floor across the
+// middle of the hearth, so flames rise straight from the ground
+func (f *Fire) fuelStrip() []int {
+	fuel := make([]int, f.width*f.height)
+	span := f.hearthRight - f.hearthLeft
+	left := f.hearthLeft + span/5
+	right := f.hearthRight - span/5
+	for i := range min(fuelStripRows, f.height) {
+		y := f.height - 1 - i
+		if f.down {
+			y = i
+		}
+		for x := left; x < right; x++ {
+			fuel[y*f.width+x] = 1
+		}
+	}
+	return fuel
+}
+
+// BurnedOut reports whether the fuel has burned away to nothing. A fire with
+// no logs never burns out.
+func (f *Fire) BurnedOut() bool {
+	for _, id := range f.fuelMap {
+		if id != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// flipLog mirrors a log top to bottom, hanging it from the ceiling
+func (f *Fire) flipLog(l *Log) {
+	top := float64(f.height - 1)
+	l.MidY = top - l.MidY
+	l.y1, l.y2 = top-l.y1, top-l.y2
+	l.dy = -l.dy
+	l.Angle = -l.Angle
+}
+
+// hearthLogs stacks sticks in balanced pairs across the hearth, densest and
+// tallest in the middle
+func (f *Fire) hearthLogs(baseRadius float64) []Log {
+	centerX := float64(f.hearthLeft+f.hearthRight) / 2.0
+	bottomY := float64(f.height - 1)
+
+	tempLogs := []Log{}
+	numLogs := min(f.width, 120)
+	if f.Settings.Logs > 0 {
+		numLogs = min(f.Settings.Logs, MaxLogs)
+	}
+	// Ensure we have an even number for pairing
+	if numLogs%2 != 0 {
+		numLogs++
+	}
+	sigmaX := float64(f.width) * 0.25
+
+	// 1. Generate sticks in pairs to ensure balance
+	for i := 0; i < numLogs; i += 2 {
+		// Sample a distance from center
+		offset := math.Abs(f.rng.NormFloat64() * sigmaX)
+		// Attempt to place a pair (left and right)
+		for side := range []int{0, 1} {
+			var midX, midY float64
+			var length, angle, r float64
+			dir := 1.0
+
+			if side == 0 {
+				dir = -1.0
+			}
+
+			maxAttempts := 15
+
+			for attempt := range make([]struct{}, maxAttempts) {
+				// Each side gets its own variation but same horizontal distance magnitude
+				thisOffset := offset * (0.9 + f.rng.Float64()*0.2)
+				midX = centerX + (dir * thisOffset)
+				distFromCenter := (midX - centerX) / sigmaX
+
+				maxH := (float64(f.height) / 3.0) * math.Exp(-distFromCenter*distFromCenter*0.8)
+				length = 7.0 + f.rng.Float64()*12.0
+
+				angle = (f.rng.Float64() - 0.5) * math.Pi * 0.6
+				r = baseRadius * (0.6 + f.rng.Float64()*0.8)
+				limitY := bottomY - r - 0.5
+				hRange := maxH
+
+				if hRange > limitY {
+					hRange = limitY
+				}
+				midY = limitY - f.rng.Float64()*hRange
+				if len(tempLogs) < f.Settings.CoreLogs {
+					// Seed the first few sticks near the center
+					if math.Abs(midX-centerX) < f.Settings.CoreSpread {
+						break
+					}
+					continue
+
+				}
+
+				// Proximity check
+				isNear := false
+				proximityLimit := length * 1.5 * f.Settings.LogSpacing
+				for _, existing := range tempLogs {
+					dx := midX - existing.MidX
+					dy := midY - existing.MidY
+					if dx*dx+dy*dy < proximityLimit*proximityLimit {
+						isNear = true
+						break
+					}
+				}
+
+				if isNear || attempt == maxAttempts-1 {
+					break
+				}
+			}
+			tempLogs = append(tempLogs, Log{
+				MidX: midX, MidY: midY,
+				Angle: angle, Length: length, R: r,
+				depth: midY, ID: len(tempLogs) + 1,
+			})
+		}
+	}
+
+	// 2. Adjust angles: if nothing is underneath the center, make it
+	// horizontal, unless the logs are to lie as they were tossed
+	for i := range tempLogs {
+		underneath := false
+		for j := range tempLogs {
+			if i == j {
+				continue
+			}
+			// Check if log j is "under" log i (larger Y, similar X)
+			// Using a small horizontal window to define "under"
+			if tempLogs[j].MidY > tempLogs[i].MidY+0.5 &&
+				math.Abs(tempLogs[j].MidX-tempLogs[i].MidX) < tempLogs[i].Length/3.0 {
+				underneath = true
+				break
+			}
+		}
+
+		if !underneath && !f.Settings.NoFlatten {
+			tempLogs[i].Angle = 0
+			// If it's the bottom stick, make sure it's actually near the bottom
+			// to look like it's resting on the floor.
+			if tempLogs[i].MidY > bottomY-5.0 {
+				tempLogs[i].MidY = bottomY - tempLogs[i].R - 0.2
+			}
+		}
+	}
+
+	// 3. Place the ends on the final angles, keeping the logs on screen. This
+	// comes after all the flattening, which compares the centers as they
+	// were generated, so it stores the clamped centers back to match.
+	for i := range tempLogs {
+		// Recalculate x1, y1, x2, y2 based on final angle
+		dx := math.Cos(tempLogs[i].Angle) * tempLogs[i].Length / 2.0
+		dy := math.Sin(tempLogs[i].Angle) * tempLogs[i].Length / 2.0 / aspect
+
+		// Horizontal clamping
+		mx := tempLogs[i].MidX
+		r := tempLogs[i].R
+		if mx-math.Abs(dx)-r < 0 {
+			mx = math.Abs(dx) + r
+		}
+		if mx+math.Abs(dx)+r > float64(f.width-1) {
+			mx = float64(f.width-1) - math.Abs(dx) - r
+		}
+
+		// Vertical clamping so steep logs don't sink through the floor
+		my := tempLogs[i].MidY
+		if my+math.Abs(dy)+r > bottomY {
+			my = bottomY - math.Abs(dy) - r
+		}
+
+		tempLogs[i].MidX, tempLogs[i].MidY = mx, my
+		tempLogs[i].x1 = mx - dx
+		tempLogs[i].y1 = my - dy
+		tempLogs[i].x2 = mx + dx
+		tempLogs[i].y2 = my + dy
+	}
+
+	return tempLogs
+}
+
+// teepeeLogs leans sticks inward so they cross over the hearth center, with
+// a couple laid flat across their feet
+func (f *Fire) teepeeLogs(baseRadius float64) []Log {
+	centerX := float64(f.hearthLeft+f.hearthRight) / 2.0
+	bottomY := float64(f.height - 1)
+	halfBase := float64(f.hearthRight-f.hearthLeft) * 0.3
+	apexRise := math.Min(halfBase*1.2/aspect, float64(f.height)/2.0)
+
+	numLogs := 8
+	if f.Settings.Logs > 0 {
+		numLogs = min(f.Settings.Logs, MaxLogs)
+	}
+
+	tempLogs := []Log{}
+	for i := range numLogs {
+		// Spread the feet evenly from left to right around the center
+		t := -1.0 + 2.0*(float64(i)+0.5)/float64(numLogs) + (f.rng.Float64()-0.5)*0.1
+		r := baseRadius * (0.8 + f.rng.Float64()*0.4)
+
+		footX := math.Max(r, math.Min(float64(f.width-1)-r, centerX+t*halfBase))
+		footY := bottomY - r
+		tipX := centerX - t*1.5
+		tipY := bottomY - apexRise*(0.85+f.rng.Float64()*0.15)
+
+		angle := math.Atan2((tipY-footY)*aspect, tipX-footX)
+		length := math.Hypot(tipX-footX, (tipY-footY)*aspect)
+
+		// Random depths interleave the front and back sticks
+		tempLogs = append(tempLogs, Log{
+			MidX: (footX + tipX) / 2, MidY: (footY + tipY) / 2,
+			Angle: angle, Length: length, R: r, depth: f.rng.Float64(),
+			x1: footX, y1: footY, x2: tipX, y2: tipY,
+		})
+	}
+
+	// Two flat sticks across the feet, in front of the rest
+	for _, dir := range []float64{-1, 1} {
+		r := baseRadius * 1.2
+		y := bottomY - r
+		x1 := centerX + dir*halfBase*0.9
+		x2 := centerX - dir*halfBase*0.2
+		tempLogs = append(tempLogs, Log{
+			MidX: (x1 + x2) / 2, MidY: y,
+			Length: math.Abs(x2 - x1), R: r, depth: 2,
+			x1: x1, y1: y, x2: x2, y2: y - dir*0.3,
+		})
+	}
+
+	return tempLogs
+}
@@ -0,0 +1,175 @@
+package fireplace
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// checkLayout reports the first way the generated wood breaks the layout's
+// invariants, or nil if it holds them all.
+//
+//   - woodMap covers the grid exactly.
+//   - The logs are numbered 1 to logCount in the order they're stacked.
+//   - Every cell shows the nearest log that covers it, or no wood. A log may
+//     be hidden entirely behind nearer ones, or fall outside the grid.
+//
+// A mask's wood isn't made of logs, so its cells aren't checked.
+func checkLayout(f *Fire) error {
+	if len(f.woodMap) != f.width*f.height {
+		return fmt.Errorf("wood map has %d cells, want %dx%d", len(f.woodMap), f.width, f.height)
+	}
+	if f.logCount != len(f.logs) {
+		return fmt.Errorf("log count is %d with %d logs", f.logCount, len(f.logs))
+	}
+	for i, l := range f.logs {
+		if l.ID != i+1 {
+			return fmt.Errorf("log %d of %d has ID %d", i+1, len(f.logs), l.ID)
+		}
+	}
+	if f.mask != nil {
+		return nil
+	}
+
+	for y := range f.height {
+		for x := range f.width {
+			want := 0
+			for i := len(f.logs) - 1; i >= 0; i-- {
+				if f.covers(&f.logs[i], x, y) {
+					want = f.logs[i].ID
+					break
+				}
+			}
+			if got := f.woodMap[y*f.width+x]; got != want {
+				return fmt.Errorf("cell (%d, %d) shows log %d, want %d", x, y, got, want)
+			}
+		}
+	}
+	return nil
+}
+
+// Grid sizes TestLayouts builds fires at: the smallest fire, odd and even
+// sizes, and extreme aspect ratios either way
+var (
+	layoutWidths  = []int{4, 5, 7, 8, 13, 40, 80, 81, 200, 400}
+	layoutHeights = []int{4, 5, 9, 24, 25, 60, 120}
+	layoutLogs    = []int{0, 1, 2, 3, 4, 7, 8} // 0 scales with the width
+)
+
+// Seeds each layout is built with
+const layoutSeeds = 3
+
+// TestLayouts builds a fire for every combination of the layout sizes, log
+// counts and both layouts with a few seeds, and checks how its logs were
+// laid out, to catch a layout change that rasterizes logs wrongly at an edge
+// case
+func TestLayouts(t *testing.T) {
+	for _, layout := range []string{"hearth", "teepee"} {
+		t.Run(layout, func(t *testing.T) {
+			for _, w := range layoutWidths {
+				for _, h := range layoutHeights {
+					for _, logs := range layoutLogs {
+						for seed := range int64(layoutSeeds) {
+							f := newTestFire(w, h, seed, func(s *Settings) { s.Layout, s.Logs = layout, logs })
+							if err := checkLayout(f); err != nil {
+								t.Errorf("%dx%d, %d logs, seed %d: %v", w, h, logs, seed, err)
+							}
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestLogHeights checks the cached fuel height of every column against a
+// fresh scan of the fuel map, after each way the wood can change
+func TestLogHeights(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Settings)
+		act    func(*Fire)
+	}{
+		{"generated", nil, func(*Fire) {}},
+		{"teepee", func(s *Settings) { s.Layout = "teepee" }, func(*Fire) {}},
+		{"down", func(s *Settings) { s.Down = true }, func(*Fire) {}},
+		{"no logs", func(s *Settings) { s.NoLogs = true }, func(*Fire) {}},
+		{"log added", nil, func(f *Fire) { f.AddLog() }},
+		{"hearth moved", nil, func(f *Fire) { f.MoveHearth(-7) }},
+		{"burned down", func(s *Settings) { s.Consume = true }, func(f *Fire) {
+			for range 600 {
+				f.Step()
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, size := range [][2]int{{4, 4}, {13, 9}, {80, 24}, {200, 60}} {
+				f := newTestFire(size[0], size[1], 1, tt.change)
+				tt.act(f)
+				for x := range f.width {
+					if got, want := f.getLogHeight(x), f.scanLogHeight(x); got != want {
+						t.Errorf("%dx%d: column %d has cached height %d, scanned %d", size[0], size[1], x, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestFloorClamp builds hearth fires whose logs keep the steepest angles
+// they're tossed at, across sizes and seeds, and checks no log's end reaches
+// through the floor and every log's center is still the middle of its ends
+func TestFloorClamp(t *testing.T) {
+	const eps = 1e-9
+	for _, size := range [][2]int{{8, 4}, {13, 9}, {40, 12}, {80, 24}, {200, 60}} {
+		for _, logs := range []int{0, 3, 8} {
+			for seed := range int64(20) {
+				f := newTestFire(size[0], size[1], seed, func(s *Settings) { s.NoFlatten, s.Logs = true, logs })
+				floor := float64(f.height - 1)
+				for _, l := range f.logs {
+					x1, y1, x2, y2 := l.Ends()
+					if low := max(y1, y2) + l.R; low > floor+eps {
+						t.Errorf("%dx%d, %d logs, seed %d: log %d at angle %.2f reaches %.2f, below the floor at %g",
+							size[0], size[1], logs, seed, l.ID, l.Angle, low, floor)
+					}
+					if math.Abs((x1+x2)/2-l.MidX) > eps || math.Abs((y1+y2)/2-l.MidY) > eps {
+						t.Errorf("%dx%d, %d logs, seed %d: log %d is centered at (%.2f, %.2f), its ends at (%.2f, %.2f)",
+							size[0], size[1], logs, seed, l.ID, l.MidX, l.MidY, (x1+x2)/2, (y1+y2)/2)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestConsumeMoved moves the hearth under a single log and heats only the
+// cell at the log's center where it's drawn, then checks the log burns down
+// at that heat rather than smoldering from the cold cell it was laid over.
+// The log runs along row 20 from column 30 to 40, and its wood reaches from
+// column 28 to 42, so the hearth stops after moving 37 right or 28 left.
+func TestConsumeMoved(t *testing.T) {
+	tests := []struct {
+		dx, x int // The move and the column the log's center is drawn at
+	}{
+		{0, 35}, {17, 52}, {-17, 18}, {500, 72}, {-500, 7},
+	}
+	for _, tt := range tests {
+		f := newTestFire(80, 24, 1, func(s *Settings) { s.Consume = true }, WithLogs([]Log{NewLog(30, 20, 40, 20, 1)}))
+		if f.bedLeft != 28 || f.bedRight != 42 {
+			t.Fatalf("the log's wood reaches from column %d to %d, want 28 to 42", f.bedLeft, f.bedRight)
+		}
+		f.MoveHearth(tt.dx)
+
+		l := &f.logs[0]
+		clear(f.fire)
+		f.setHeat(tt.x, 40, 36)
+
+		before := l.burned
+		f.consumeLogs()
+		if want := before + l.burnRate; math.Abs(l.burned-want) > 1e-12 {
+			t.Errorf("hearth moved %d: log burned to %g, want %g from the heat at column %d", tt.dx, l.burned, want, tt.x)
+		}
+	}
+}
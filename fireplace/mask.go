@@ -0,0 +1,46 @@
+package fireplace
+
+import "image"
+
+// paintMask draws Settings.Mask into woodMap as wood wherever it's dark,
+// scaled to fit the fire standing on the floor and centered on the hearth.
+// Burning down, it hangs upside down from the ceiling like the logs would.
+func (f *Fire) paintMask() {
+	bounds := f.mask.Bounds()
+	iw, ih := float64(bounds.Dx()), float64(bounds.Dy())
+	if iw == 0 || ih == 0 {
+		return
+	}
+
+	// Work in square units, with cells aspect times taller than wide
+	areaW, areaH := float64(f.width), float64(f.height)*aspect
+	scale := min(areaW/iw, areaH/ih)
+	left := float64(f.hearthLeft+f.hearthRight)/2 - iw*scale/2
+	top := areaH - ih*scale
+
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			px := int((float64(x)+0.5-left)/scale) + bounds.Min.X
+			py := int(((float64(y)+0.5)*aspect-top)/scale) + bounds.Min.Y
+			if !(image.Point{px, py}).In(bounds) || !isDark(f.mask, px, py) {
+				continue
+			}
+
+			row := y
+			if f.down {
+				row = f.height - 1 - y
+			}
+			f.woodMap[row*f.width+x] = 1
+		}
+	}
+}
+
+// isDark reports whether the pixel at (x, y) is dark and mostly opaque
+func isDark(img image.Image, x, y int) bool {
+	r, g, b, a := img.At(x, y).RGBA()
+	if a < 0x8000 {
+		return false
+	}
+	luma := (299*r + 587*g + 114*b) / 1000
+	return luma < 0x8000
+}
@@ -0,0 +1,40 @@
+package fireplace
+
+import "github.com/gdamore/tcell/v2"
+
+// How opaque the reflection is right at the floor. It fades out from there to
+// nothing at the last row drawn.
+const reflectOpacity = 0.45
+
+// DrawReflection draws the fire mirrored in a shiny floor onto c, in the rows
+// rows starting at (left, top), which belong just under the fire. The flames
+// nearest the floor show first, softened and laid faintly over black. A fire
+// burning down from the ceiling, or drawn in ASCII, has nothing to reflect
+// in.
+func (f *Fire) DrawReflection(c Canvas, left, top, rows int) {
+	if f.down || f.Settings.ASCII || rows <= 0 {
+		return
+	}
+
+	black := tcell.NewRGBColor(0, 0, 0)
+	span := float64(2 * rows)
+	for y := range rows {
+		for x := range f.width {
+			var halves [2]tcell.Color
+			for i := range halves {
+				// Sub-pixels below the floor mirror those as far above it
+				k := 2*y + i
+				sy := f.fireHeight - 1 - k
+
+				// A little blur across the row softens the reflection
+				heat := (f.heatAt(max(x-1, 0), sy) + 2*f.heatAt(x, sy) + f.heatAt(min(x+1, f.width-1), sy)) / 4
+				halves[i] = black
+				if heat >= 4 {
+					limit := min(f.Settings.BlendCap, 1) * reflectOpacity * (1 - float64(k)/span)
+					halves[i] = blendColors(black, f.Settings.Palette[clamp(heat, 0, 36)], heat, limit)
+				}
+			}
+			c.SetContent(left+x, top+y, '▀', nil, tcell.StyleDefault.Foreground(halves[0]).Background(halves[1]))
+		}
+	}
+}
@@ -0,0 +1,522 @@
+package fireplace
+
+import (
+	"cmp"
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Doom fire palette definition (RGB) - No white/yellow
+var DoomPalette = []uint32{
+	0x070707, 0x1F0707, 0x2F0F07, 0x470F07, 0x571707, 0x671F07, 0x771F07, 0x8F2707,
+	0x9F2F07, 0xAF3F07, 0xBF4707, 0xC74707, 0xDF4F07, 0xDF5707, 0xDF5707, 0xD75F07,
+	0xD75F07, 0xD7670F, 0xCF6F0F, 0xCF770F, 0xCF7F0F, 0xCF8717, 0xC78717, 0xC78F17,
+	0xC7971F, 0xBF9F1F, 0xBF9F1F, 0xBFA727, 0xBFA727, 0xBFAF2F, 0xB7B72F, 0xB7B737,
+	0xAF3F07, 0xAF3F07, 0xAF3F07, 0xAF3F07,
+}
+
+// NewPalette expands up to 36 RGB colors into the 37 heat colors, with black
+// for heat 0
+func NewPalette(hexes []uint32) []tcell.Color {
+	c := make([]tcell.Color, 37) // 0 to 36
+	// Fill 0 with black
+	c[0] = tcell.NewRGBColor(0, 0, 0)
+	for i := 1; i < len(c); i++ {
+		c[i] = c[0]
+	}
+
+	for i, hex := range hexes {
+		if i+1 >= len(c) {
+			break
+		}
+		c[i+1] = tcell.NewHexColor(int32(hex))
+	}
+	return c
+}
+
+// cell is one character of a rendered frame
+type cell struct {
+	r     rune
+	style tcell.Style
+}
+
+// Render draws the fire, calling setCell for every cell with (0, 0) at the
+// top-left. Cells with nothing in them are spaces on the default colors.
+func (f *Fire) Render(setCell func(x, y int, fg, bg tcell.Color, r rune)) {
+	for i := range f.cells {
+		f.cells[i] = cell{' ', tcell.StyleDefault}
+	}
+
+	if f.Settings.ASCII {
+		f.drawASCII()
+	} else {
+		if f.Settings.Ambient {
+			f.drawAmbient()
+		}
+
+		// 1. Draw all sticks first to establish the woodMap on the screen
+		f.drawEnvironment(1, f.woodIDs())
+		if f.Settings.FloorLine {
+			f.drawFloorLine()
+		}
+
+		// 2. Draw fire with blending logic
+		f.drawFireBlended()
+	}
+	f.drawEmbers()
+	if f.Settings.Haze {
+		f.drawHaze()
+	}
+
+	for i, c := range f.cells {
+		fg, bg, _ := c.style.Decompose()
+		x, y := i%f.width, i/f.width
+		if f.Settings.Vignette > 0 && !f.Settings.ASCII {
+			v := f.vignette(x, y)
+			fg, bg = dimColor(fg, v), dimColor(bg, v)
+		}
+		setCell(x, y, fg, bg, c.r)
+	}
+}
+
+// vignette is how bright cell (x, y) is left by Settings.Vignette, from 1 at
+// the middle of the frame falling away with the square of the distance to
+// 1 - Vignette at the corners. It's only applied to the colors drawn, after
+// everything else, so the simulation never sees it.
+func (f *Fire) vignette(x, y int) float64 {
+	cx, cy := float64(f.width-1)/2, float64(f.height-1)/2
+	dx, dy := 0.0, 0.0
+	if cx > 0 {
+		dx = (float64(x) - cx) / cx
+	}
+	if cy > 0 {
+		dy = (float64(y) - cy) / cy
+	}
+	return 1 - clamp(f.Settings.Vignette, 0, 1)*(dx*dx+dy*dy)/2
+}
+
+// dimColor scales c's brightness by v. The terminal's default color has no
+// value to scale, so it's left alone.
+func dimColor(c tcell.Color, v float64) tcell.Color {
+	if c == tcell.ColorDefault {
+		return c
+	}
+	r, g, b := c.RGB()
+	return tcell.NewRGBColor(int32(float64(r)*v), int32(float64(g)*v), int32(float64(b)*v))
+}
+
+// setContent puts a character into the frame being rendered
+func (f *Fire) setContent(x, y int, r rune, style tcell.Style) {
+	f.cells[y*f.width+x] = cell{r, style}
+}
+
+func (f *Fire) drawFireBlended() {
+	ramp := []rune(f.Settings.GlyphRamp)
+
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			sy1 := y * 2
+			sy2 := y*2 + 1
+
+			heat1 := f.drawHeat(x, sy1)
+			heat2 := f.drawHeat(x, sy2)
+
+			// Only process if there is actual heat to display
+			if heat1 < 4 && heat2 < 4 {
+				continue
+			}
+
+			// Get existing color from the sticks
+			_, existingBg, _ := f.cells[y*f.width+x].style.Decompose()
+
+			// FIX: Ensure we don't blend with the terminal's default white/grey
+			// If it's the default background, treat it as black
+			if existingBg == tcell.ColorWhite || existingBg == tcell.ColorDefault {
+				existingBg = tcell.ColorBlack
+			}
+
+			// With a glyph ramp the whole cell shows its hotter half as a
+			// character of matching density over the background
+			if len(ramp) > 0 {
+				heat := max(heat1, heat2)
+				c := f.subPixelColor(existingBg, heat)
+				if f.Settings.Dither {
+					c = dither(c, x, sy1)
+				}
+				char := ramp[clamp(heat, 0, 36)*(len(ramp)-1)/36]
+				f.setContent(x, y, char, tcell.StyleDefault.Foreground(c).Background(existingBg))
+				continue
+			}
+
+			// Each half of the cell shows only its own sub-pixel, blended over
+			// the background. The foreground of a wood cell just colors its
+			// texture glyph, so it isn't what shows through the top half.
+			c1 := f.subPixelColor(existingBg, heat1)
+			c2 := f.subPixelColor(existingBg, heat2)
+			if f.Settings.Dither {
+				c1 = dither(c1, x, sy1)
+				c2 = dither(c2, x, sy2)
+			}
+
+			// A tip that cools away from the fuel fills the cell from the
+			// fuel's side to as far as its heat reaches. Burning down it hangs
+			// from the top, and with no upper eighths to draw that with, the
+			// lower block for the rest of the cell is drawn in the background
+			// over the flame.
+			if f.Settings.Subcell && !f.down && heat1 <= heat2 {
+				if n := tipFill(heat1, heat2); n < 8 {
+					f.setContent(x, y, lowerEighths[n-1], tcell.StyleDefault.Foreground(c2).Background(existingBg))
+					continue
+				}
+			}
+			if f.Settings.Subcell && f.down && heat2 <= heat1 {
+				if n := tipFill(heat2, heat1); n < 8 {
+					f.setContent(x, y, lowerEighths[7-n], tcell.StyleDefault.Foreground(existingBg).Background(c1))
+					continue
+				}
+			}
+
+			// A lower half block takes the bottom half as its foreground
+			if f.Settings.Block == "lower" {
+				f.setContent(x, y, '▄', tcell.StyleDefault.Foreground(c2).Background(c1))
+				continue
+			}
+			f.setContent(x, y, '▀', tcell.StyleDefault.Foreground(c1).Background(c2))
+		}
+	}
+}
+
+// Lower blocks from one eighth to seven eighths full. Half full is the
+// same '▄' the half-block renderer uses.
+var lowerEighths = []rune("▁▂▃▄▅▆▇")
+
+// Heat above the visible threshold it takes to fill a half cell in --subcell
+// mode
+const subcellSpan = 8
+
+// halfFill is how many eighths of its half cell a visible sub-pixel fills,
+// from 1 to 4
+func halfFill(heat int) int {
+	return clamp((heat-4)*4/subcellSpan+1, 1, 4)
+}
+
+// tipFill is how many eighths of a cell to fill for a flame whose lower half
+// has heat bottom and upper half heat top, no hotter. The upper half only
+// adds to a full lower half; 8 means the cell is solid flame.
+func tipFill(top, bottom int) int {
+	n := halfFill(bottom)
+	if n == 4 && top >= 4 {
+		n += halfFill(top)
+	}
+	return n
+}
+
+// drawAmbient fills the region's background with a very dark gradient, cool
+// at the top and faintly warm near the hearth. It stays close enough to black
+// that drawFireBlended still composites flames over it cleanly.
+func (f *Fire) drawAmbient() {
+	centerX := float64(f.hearthLeft+f.hearthRight) / 2.0
+	halfWidth := math.Max(float64(f.width)/2.0, 1)
+	bottom := float64(max(f.height-1, 1))
+
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			dist := math.Min(math.Abs(float64(x)-centerX)/halfWidth, 1)
+			lit := float64(y)
+			if f.down {
+				lit = bottom - lit
+			}
+			warmth := (lit / bottom) * (1 - dist*0.7)
+
+			r := int32(6 + warmth*22)
+			g := int32(6 + warmth*8)
+			b := int32(12 - warmth*6)
+
+			style := tcell.StyleDefault.Background(tcell.NewRGBColor(r, g, b))
+			f.setContent(x, y, ' ', style)
+		}
+	}
+}
+
+// drawHeat returns the heat to display for sub-pixel row sy of column x. With
+// --smooth it's taken from a small vertical blur of the column, which only
+// changes what is drawn, never the simulation.
+func (f *Fire) drawHeat(x, sy int) int {
+	h := f.heatAt(x, sy)
+	if f.Settings.Smooth {
+		up, down := h, h
+		if sy > 0 {
+			up = f.heatAt(x, sy-1)
+		}
+		if sy+1 < f.fireHeight {
+			down = f.heatAt(x, sy+1)
+		}
+		h = (up + 2*h + down) / 4
+	}
+
+	if f.Settings.Floor > 0 && f.inBed(x, sy/2) {
+		h = max(h, f.Settings.Floor)
+	}
+	return h
+}
+
+// floorRow returns the row the fire rests on: the bottom one, or the top one
+// for a fire burning down from the ceiling
+func (f *Fire) floorRow() int {
+	if f.down {
+		return 0
+	}
+	return f.height - 1
+}
+
+// Ash colors for the floor line, and the glow it takes under the hottest fire
+var (
+	ashColor   = tcell.NewRGBColor(30, 28, 27)
+	emberColor = tcell.NewRGBColor(160, 60, 12)
+)
+
+// drawFloorLine lays a row of ash along the floor, under the logs resting on
+// it. A few cells are embers, which glow dimly orange as the fire over them
+// gets hotter, and the rest are grey ash that barely warms.
+func (f *Fire) drawFloorLine() {
+	y := f.floorRow()
+	for x := 0; x < f.width; x++ {
+		// The floor's own sub-pixel row is never heated, so take the
+		// warmth from the rows just above it
+		heat := 0
+		for d := 1; d <= 3; d++ {
+			heat = max(heat, f.heatAt(x, f.fireRow(f.fireHeight-1-d)))
+		}
+		warmth := float64(clamp(heat, 0, 36)) / 36
+
+		noise := (x*17 + 5) % 11
+		glow := warmth * 0.15
+		if noise < 3 {
+			glow = warmth * 0.8
+		}
+		c := mixColor(ashColor, emberColor, glow)
+
+		char := ' '
+		if noise == 7 {
+			char = '.'
+		}
+		f.setContent(x, y, char, tcell.StyleDefault.Background(c).Foreground(mixColor(c, tcell.ColorWhite, 0.15)))
+	}
+}
+
+// mixColor blends from a toward b by t
+func mixColor(a, b tcell.Color, t float64) tcell.Color {
+	r1, g1, b1 := a.RGB()
+	r2, g2, b2 := b.RGB()
+	return tcell.NewRGBColor(
+		int32(float64(r1)+float64(r2-r1)*t),
+		int32(float64(g1)+float64(g2-g1)*t),
+		int32(float64(b1)+float64(b2-b1)*t),
+	)
+}
+
+// inBed reports whether cell (x, y) lies within the log bed: between the
+// outermost wood columns and no further from the floor than the wood in its
+// column reaches
+func (f *Fire) inBed(x, y int) bool {
+	if x < f.bedLeft || x > f.bedRight {
+		return false
+	}
+	if f.down {
+		return y <= f.getLogHeight(x)
+	}
+	return y >= f.height-1-f.getLogHeight(x)
+}
+
+// subPixelColor maps one half-cell's heat to its color. Cold halves keep the
+// base color untouched so they never pick up a tint or a flare.
+func (f *Fire) subPixelColor(base tcell.Color, heat int) tcell.Color {
+	if heat < 4 {
+		return base
+	}
+
+	// Flash visible flames brighter for a crackle flare
+	heat = clamp(heat+f.Settings.Flare, 0, 36)
+
+	// Blend fire colors with existing stick/background colors
+	return blendColors(base, f.Settings.Palette[heat], heat, f.Settings.BlendCap)
+}
+
+// bayer4 is a 4x4 ordered-dither threshold matrix
+var bayer4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// ditherStep is roughly the gap between levels of the 256-color cube, which
+// the terminal quantizes our RGB to
+const ditherStep = 40.0
+
+// dither nudges c by a fixed offset for its sub-pixel position, so bands in
+// the gradient break up into a stable pattern once the terminal quantizes it
+func dither(c tcell.Color, x, y int) tcell.Color {
+	offset := int32(((bayer4[y&3][x&3]+0.5)/16 - 0.5) * ditherStep)
+	r, g, b := c.RGB()
+	return tcell.NewRGBColor(clamp(r+offset, 0, 255), clamp(g+offset, 0, 255), clamp(b+offset, 0, 255))
+}
+
+// blendColors lays a flame's color over base, more opaquely the hotter it
+// is, up to limit. The hottest heat, 36, is 0.9 opaque, so any limit above
+// that looks the same.
+func blendColors(base, overlay tcell.Color, heat int, limit float64) tcell.Color {
+	// If no heat, return the base (wood or black)
+	if heat <= 0 {
+		return base
+	}
+
+	br, bg, bb := base.RGB()
+	or, og, ob := overlay.RGB()
+
+	// Use heat as the blend factor
+	alpha := float64(heat) / 40.0
+
+	// Ensure high heat doesn't blow out to white by capping the intensity
+	if alpha > limit {
+		alpha = limit
+	}
+
+	r := int32(float64(br)*(1.0-alpha) + float64(or)*alpha)
+	g := int32(float64(bg)*(1.0-alpha) + float64(og)*alpha)
+	b := int32(float64(bb)*(1.0-alpha) + float64(ob)*alpha)
+
+	return tcell.NewRGBColor(clamp(r, 0, 255), clamp(g, 0, 255), clamp(b, 0, 255))
+}
+
+// WoodColors names the wood tints Settings.WoodColor can pick
+var WoodColors = []string{"oak", "ash", "ember", "birch"}
+
+// woodTint is the color of a kind of wood: its RGB at the back of the pile,
+// what the frontmost log adds to that, and how the dark cells of its bark
+// compare with the texture's usual shade
+type woodTint struct {
+	back, front [3]float64
+	dark        float64
+}
+
+var woodTints = map[string]woodTint{
+	"oak":   {[3]float64{25, 15, 10}, [3]float64{35, 20, 10}, 1},    // Dark browns
+	"ash":   {[3]float64{28, 26, 25}, [3]float64{32, 30, 28}, 0.85}, // Burnt grey
+	"ember": {[3]float64{50, 16, 6}, [3]float64{45, 18, 4}, 1.2},    // Charred and glowing orange
+	"birch": {[3]float64{60, 54, 44}, [3]float64{55, 50, 42}, 0.6},  // Pale, with dark flecks
+}
+
+// Bark glyphs in the order they appear as the texture gets heavier. The
+// first five make up the standard bark.
+var barkRunes = []rune{'.', ',', '\'', '`', '.', ':', ';', '~', '"', '^'}
+
+// barkAt picks the texture glyph for wood cell (x, y) of log logID, and whether
+// the cell is a dark one, showing the base color through its glyph rather
+// than on its background. It depends only on position and texture, never on
+// heat, so the bark pattern holds still while the glow under it changes.
+func barkAt(x, y, logID, shown int) (rune, bool) {
+	noise := (x*13 + y*37 + logID*7) % 10
+
+	// Texture characters
+	char := ' '
+	if rank := (noise + 8) % 10; rank < shown {
+		char = barkRunes[rank]
+	}
+	return char, noise > 5
+}
+
+func (f *Fire) drawEnvironment(minID, maxID int) {
+	tint, ok := woodTints[f.Settings.WoodColor]
+	if !ok {
+		tint = woodTints["oak"]
+	}
+
+	// Texture 1 marks half the cells and halves the dark cells' brightness
+	texture := clamp(f.Settings.Texture, 0, 2)
+	shown := int(math.Round(texture * 5))
+	shade := min((1-texture/2)*tint.dark, 1)
+	ids := f.woodIDs()
+
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			logID := f.woodAt(x, y)
+
+			if logID >= minID && logID <= maxID {
+				depth := float64(logID) / float64(ids)
+
+				// Base stick colors, brighter toward the front
+				br := int32(tint.back[0] + depth*tint.front[0])
+				bg := int32(tint.back[1] + depth*tint.front[1])
+				bb := int32(tint.back[2] + depth*tint.front[2])
+
+				// A nearer log lying against this one shades it, and the
+				// wood's own brightness scales what's left. Neither touches
+				// the glow.
+				light := f.Settings.WoodLight
+				if n := f.occluders(x, y, logID); n > 0 {
+					light *= 1 - contactShadow*float64(n)
+				}
+				if light != 1 {
+					br = int32(float64(br) * light)
+					bg = int32(float64(bg) * light)
+					bb = int32(float64(bb) * light)
+				}
+
+				// Get local fire heat for glow
+				heat1 := f.heatAt(x, y*2)
+				heat2 := f.heatAt(x, y*2+1)
+				avgHeat := (heat1 + heat2) / 2
+
+				// Add fire glow to the stick
+				glow := f.Settings.LogGlow
+				r := br + int32(float64(avgHeat)*glow[0])
+				g := bg + int32(float64(avgHeat)*glow[1])
+				b := bb + int32(float64(avgHeat)*glow[2])
+
+				baseColor := tcell.NewRGBColor(clamp(r, 0, 255), clamp(g, 0, 255), clamp(b, 0, 255))
+				darkColor := tcell.NewRGBColor(
+					clamp(int32(float64(r)*shade), 0, 255),
+					clamp(int32(float64(g)*shade), 0, 255),
+					clamp(int32(float64(b)*shade), 0, 255),
+				)
+
+				// Only the colors take the glow; the layout stays put
+				char, dark := barkAt(x, y, logID, shown)
+				var style tcell.Style
+				if dark {
+					style = tcell.StyleDefault.Background(darkColor).Foreground(baseColor)
+				} else {
+					style = tcell.StyleDefault.Background(baseColor).Foreground(darkColor)
+				}
+
+				f.setContent(x, y, char, style)
+			}
+		}
+	}
+}
+
+// How much each nearer log bordering a wood cell darkens it
+const contactShadow = 0.25
+
+// occluders counts the cells above and to the left of wood cell (x, y) that
+// belong to a log nearer than logID, from 0 to 2. The light comes from the
+// upper left, so those are the sides a nearer log can shadow it from.
+func (f *Fire) occluders(x, y, logID int) int {
+	n := 0
+	if f.woodAt(x, y-1) > logID {
+		n++
+	}
+	if f.woodAt(x-1, y) > logID {
+		n++
+	}
+	return n
+}
+
+// clamp limits v to the range lo to hi. Heat is clamped to 0-36 before it
+// indexes the palette and color channels to 0-255 before they become a color,
+// since glow and flares can push either past its range.
+func clamp[T cmp.Ordered](v, lo, hi T) T {
+	return min(max(v, lo), hi)
+}
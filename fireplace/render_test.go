@@ -0,0 +1,208 @@
+package fireplace
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Frames TestFrameHash steps and draws
+const hashFrames = 40
+
+// TestFrameHash steps and renders fires from a fixed seed and compares an
+// FNV-1a hash of every cell of every frame, its character and colors,
+// against the known one. A different hash means the simulation or the
+// rendering changed: if that was meant, look at the frames and update the
+// golden hash.
+func TestFrameHash(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Settings)
+		want   string
+	}{
+		{"default", nil, "4fde794c1e4a57d6"},
+		{"ascii", func(s *Settings) { s.ASCII = true }, "98fa8297a65ee12a"},
+		{"subcell and smooth", func(s *Settings) { s.Subcell, s.Smooth, s.Ambient = true, true, true }, "8241abaeecf6fd1f"},
+		{"teepee with embers", func(s *Settings) { s.Layout, s.EmberRate = "teepee", 1 }, "e2f03a628b89b46b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFire(80, 24, 1, tt.change)
+
+			h := fnv.New64a()
+			var b [4]byte
+			for range hashFrames {
+				f.Step()
+				f.Render(func(x, y int, fg, bg tcell.Color, r rune) {
+					for _, v := range []int32{r, fg.Hex(), bg.Hex()} {
+						binary.LittleEndian.PutUint32(b[:], uint32(v))
+						h.Write(b[:])
+					}
+				})
+			}
+
+			if got := fmt.Sprintf("%016x", h.Sum64()); got != tt.want {
+				t.Errorf("frame hash %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHalfBlocks sets each combination of a hot and a cold sub-pixel in the
+// two halves of a wood cell and an empty one, and checks each half of the
+// upper half block shows only its own sub-pixel over the cell's background:
+// a cold half shows the background, never the wood glyph's color. With
+// --subcell a half-hot tip is a half block filled from the fuel's side,
+// which burning down is the top.
+func TestHalfBlocks(t *testing.T) {
+	const hot = 30
+	modes := []struct {
+		name   string
+		change func(*Settings)
+	}{
+		{"half blocks", nil},
+		{"subcell", func(s *Settings) { s.Subcell = true }},
+		{"subcell down", func(s *Settings) { s.Subcell, s.Down = true, true }},
+	}
+
+	for _, mode := range modes {
+		f := newTestFire(80, 24, 1, mode.change)
+		wood, empty := -1, -1
+		for i, id := range f.woodMap {
+			if id != 0 && wood < 0 {
+				wood = i
+			}
+			if id == 0 && empty < 0 && i/f.width < f.height-1 {
+				empty = i
+			}
+		}
+		if wood < 0 || empty < 0 {
+			t.Fatalf("%s: the fire has no wood cell or no empty one to draw over", mode.name)
+		}
+
+		for _, where := range []struct {
+			name string
+			cell int
+		}{{"wood", wood}, {"empty", empty}} {
+			x, y := where.cell%f.width, where.cell/f.width
+			for _, heats := range [][2]int{{0, 0}, {hot, 0}, {0, hot}, {hot, hot}} {
+				t.Run(fmt.Sprintf("%s %s top %d bottom %d", mode.name, where.name, heats[0], heats[1]), func(t *testing.T) {
+					clear(f.fire)
+					f.setHeat(x, y*2, heats[0])
+					f.setHeat(x, y*2+1, heats[1])
+					for i := range f.cells {
+						f.cells[i] = cell{' ', tcell.StyleDefault}
+					}
+					f.drawEnvironment(1, f.woodIDs())
+					under := f.cells[where.cell]
+					f.drawFireBlended()
+					got := f.cells[where.cell]
+
+					if heats == [2]int{0, 0} {
+						if got != under {
+							t.Errorf("a cold cell was drawn over: %q %v, want %q %v", got.r, got.style, under.r, under.style)
+						}
+						return
+					}
+					_, bg, _ := under.style.Decompose()
+					if bg == tcell.ColorDefault {
+						bg = tcell.ColorBlack
+					}
+					top, bottom := f.subPixelColor(bg, heats[0]), f.subPixelColor(bg, heats[1])
+					want, wantFg, wantBg := '▀', top, bottom
+					switch {
+					case f.Settings.Subcell && !f.Settings.Down && heats == [2]int{0, hot}:
+						want, wantFg, wantBg = '▄', bottom, bg
+					case f.Settings.Subcell && f.Settings.Down && heats == [2]int{hot, 0}:
+						// The cold lower half is drawn over the flame
+						want, wantFg, wantBg = '▄', bg, top
+					}
+					fg, gotBg, _ := got.style.Decompose()
+					if got.r != want || fg != wantFg || gotBg != wantBg {
+						t.Errorf("drew %q on %v over %v, want %q on %v over %v", got.r, fg, gotBg, want, wantFg, wantBg)
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestMaskWood renders a cold fire burning a mask, a dark square on white,
+// and checks the square is drawn as wood and nothing else is
+func TestMaskWood(t *testing.T) {
+	mask := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range mask.Pix {
+		mask.Pix[i] = 0xff
+	}
+	for y := 2; y < 6; y++ {
+		for x := 2; x < 6; x++ {
+			mask.SetGray(x, y, color.Gray{})
+		}
+	}
+	f := newTestFire(80, 24, 1, func(s *Settings) { s.Mask = mask })
+
+	// Cold, with nothing in front of it, the mask's wood is the front color
+	// of the whole stack, on the background or through a dark cell's glyph
+	tint := woodTints["oak"]
+	want := tcell.NewRGBColor(int32(tint.back[0]+tint.front[0]), int32(tint.back[1]+tint.front[1]), int32(tint.back[2]+tint.front[2]))
+	wood := 0
+	f.Render(func(x, y int, fg, bg tcell.Color, r rune) {
+		if f.woodAt(x, y) == 0 {
+			if r != ' ' || fg != tcell.ColorDefault || bg != tcell.ColorDefault {
+				t.Errorf("cell (%d, %d) outside the mask drew %q in %v on %v", x, y, r, fg, bg)
+			}
+			return
+		}
+		wood++
+		if fg != want && bg != want {
+			t.Errorf("mask cell (%d, %d) drew %q in %v on %v, want the wood's %v", x, y, r, fg, bg, want)
+		}
+	})
+	if wood == 0 {
+		t.Fatal("the mask painted no wood")
+	}
+}
+
+// TestClamp checks clamp at and either side of both bounds, for the heats,
+// color channels and fractions it's used on
+func TestClamp(t *testing.T) {
+	heats := []struct{ v, want int }{
+		{-37, 0}, {-1, 0}, {0, 0}, {1, 1}, {18, 18}, {35, 35}, {36, 36}, {37, 36}, {36 * 5, 36},
+	}
+	for _, tt := range heats {
+		if got := clamp(tt.v, 0, 36); got != tt.want {
+			t.Errorf("clamp(%d, 0, 36) = %d, want %d", tt.v, got, tt.want)
+		}
+	}
+
+	channels := []struct{ v, want int32 }{
+		{-255, 0}, {-1, 0}, {0, 0}, {128, 128}, {255, 255}, {256, 255}, {255 + 36*5, 255},
+	}
+	for _, tt := range channels {
+		if got := clamp(tt.v, 0, 255); got != tt.want {
+			t.Errorf("clamp(%d, 0, 255) = %d, want %d", tt.v, got, tt.want)
+		}
+	}
+
+	fractions := []struct{ v, want float64 }{
+		{-0.5, 0}, {-1e-9, 0}, {0, 0}, {0.25, 0.25}, {1, 1}, {1 + 1e-9, 1}, {2, 1},
+	}
+	for _, tt := range fractions {
+		if got := clamp(tt.v, 0, 1); got != tt.want {
+			t.Errorf("clamp(%g, 0, 1) = %g, want %g", tt.v, got, tt.want)
+		}
+	}
+
+	// Bounds that meet leave only the one value
+	for _, v := range []int{-1, 4, 5} {
+		if got := clamp(v, 4, 4); got != 4 {
+			t.Errorf("clamp(%d, 4, 4) = %d, want 4", v, got)
+		}
+	}
+}
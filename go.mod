@@ -5,6 +5,7 @@ go 1.25.1
 require (
 	github.com/gdamore/tcell/v2 v2.13.7
 	github.com/hajimehoshi/oto/v2 v2.4.3
+	golang.org/x/term v0.37.0
 )
 
 require (
@@ -13,6 +14,5 @@ require (
 	github.com/lucasb-eyer/go-colorful v1.3.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/sys v0.38.0 // indirect
-	golang.org/x/term v0.37.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
 )
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/term"
+)
+
+// Grid size used for headless rendering when COLUMNS and LINES aren't set
+const (
+	headlessWidth  = 80
+	headlessHeight = 24
+)
+
+// isTerminal reports whether f is attached to a terminal rather than a pipe,
+// file or other device such as /dev/null
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// headlessSize reads the grid size from COLUMNS and LINES, falling back to
+// 80x24 for anything missing or invalid
+func headlessSize() (int, int) {
+	return envSize("COLUMNS", headlessWidth), envSize("LINES", headlessHeight)
+}
+
+func envSize(name string, fallback int) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+// newHeadlessScreen returns an in-memory screen of the headless size, so the
+// frame doesn't depend on whatever terminal (if any) the process runs under
+func newHeadlessScreen() (tcell.Screen, error) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		return nil, err
+	}
+	s.SetSize(headlessSize())
+	return s, nil
+}
+
+// printStill renders a single frame and writes it to stdout as plain text
+func printStill() error {
+	drawFrame()
+
+	cells, w, h := screen.(tcell.SimulationScreen).GetContents()
+	out := bufio.NewWriter(os.Stdout)
+	for y := range h {
+		var line strings.Builder
+		for _, c := range cells[y*w : (y+1)*w] {
+			if len(c.Runes) == 0 {
+				line.WriteRune(' ')
+				continue
+			}
+			line.WriteString(string(c.Runes))
+		}
+		out.WriteString(strings.TrimRight(line.String(), " "))
+		out.WriteByte('\n')
+	}
+	return out.Flush()
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// keyBinding is one interactive control. Bindings with a zero rune are
+// handled specially by handleKey and only listed in the help.
+type keyBinding struct {
+	r      rune
+	label  string
+	help   string
+	action func()
+}
+
+// Interactive controls. The help overlay is generated from this table, so a
+// new control only needs adding here.
+var keyBindings = []keyBinding{
+	{'c', "c", "Crossfade to the next palette", cyclePalette},
+	{'<', "<", "Slow the fire down", func() { changeTimeScale(0.8) }},
+	{'>', ">", "Speed the fire up", func() { changeTimeScale(1.25) }},
+	{'+', "+", "Make the flames taller", func() { changeFlameHeight(1.1) }},
+	{'-', "-", "Make the flames shorter", func() { changeFlameHeight(1 / 1.1) }},
+	{'[', "[", "Dim the logs", func() { changeWoodLight(1 / 1.2) }},
+	{']', "]", "Brighten the logs", func() { changeWoodLight(1.2) }},
+	{'a', "a, Left", "Move the hearth left", func() { moveHearths(-hearthStep) }},
+	{'d', "d, Right", "Move the hearth right", func() { moveHearths(hearthStep) }},
+	{'s', "s", "Stoke the fire", stoke},
+	{'l', "l", "Throw another log on the fire", addLog},
+	{' ', "Space", "Pause or resume the fire", togglePause},
+	{'n', "n", "Change the fire's mood", nextMood},
+	{'m', "m", "Mute or unmute the sound", toggleMute},
+	{'y', "y", "Copy the frame to the clipboard", copyFrame},
+	{'p', "p", "Save the palette to a file", savePalette},
+	{'?', "?", "Show or hide this help", toggleHelp},
+	{0, "Ctrl+Z", "Suspend to the shell", nil},
+	{0, "", "", nil}, // Labeled and described by the quit keys
+}
+
+// keySet is a flag value naming keys, such as "q,esc,ctrl-c". A name is
+// either a single character or one of tcell's key names, in any case.
+type keySet struct {
+	names string
+	keys  map[tcell.Key]bool
+	runes map[rune]bool
+}
+
+// Keys that quit, set with --quit-key
+var quitKeys = newKeySet("esc,ctrl-c")
+
+func newKeySet(names string) keySet {
+	var k keySet
+	if err := k.Set(names); err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func (k *keySet) String() string {
+	return k.names
+}
+
+func (k *keySet) Set(s string) error {
+	keys, runes := map[tcell.Key]bool{}, map[rune]bool{}
+	for name := range strings.SplitSeq(s, ",") {
+		name = strings.TrimSpace(name)
+		if strings.EqualFold(name, "space") {
+			name = " "
+		}
+		if utf8.RuneCountInString(name) == 1 {
+			r, _ := utf8.DecodeRuneInString(name)
+			runes[r] = true
+			continue
+		}
+		key, ok := keyNamed(name)
+		if !ok {
+			return fmt.Errorf("unknown key %q", name)
+		}
+		keys[key] = true
+	}
+	k.names, k.keys, k.runes = s, keys, runes
+	return nil
+}
+
+// keyNamed looks a special key up by its tcell name, such as "Esc" or
+// "Ctrl-C", ignoring case. "escape" and "space" are accepted too.
+func keyNamed(name string) (tcell.Key, bool) {
+	switch strings.ToLower(name) {
+	case "escape":
+		return tcell.KeyEscape, true
+	}
+	for key, n := range tcell.KeyNames {
+		if strings.EqualFold(n, name) {
+			return key, true
+		}
+	}
+	return 0, false
+}
+
+// matches reports whether ev is one of the set's keys
+func (k *keySet) matches(ev *tcell.EventKey) bool {
+	if ev.Key() == tcell.KeyRune {
+		return k.runes[ev.Rune()]
+	}
+	return k.keys[ev.Key()]
+}
+
+// label lists the keys for the help, e.g. "q, Esc, Ctrl+C"
+func (k *keySet) label() string {
+	var labels []string
+	for name := range strings.SplitSeq(k.names, ",") {
+		name = strings.TrimSpace(name)
+		if key, ok := keyNamed(name); ok && utf8.RuneCountInString(name) > 1 {
+			name = strings.ReplaceAll(tcell.KeyNames[key], "-", "+")
+		}
+		labels = append(labels, name)
+	}
+	return strings.Join(labels, ", ")
+}
+
+// quitHelp describes the quit keys. Esc always closes the help, so it only
+// quits from behind it when it's one of them.
+func quitHelp() string {
+	if quitKeys.keys[tcell.KeyEscape] {
+		return "Quit (Esc closes the help first)"
+	}
+	return "Quit"
+}
+
+// checkQuitKeys makes sure no quit key is already a control, so pressing
+// one never does both
+func checkQuitKeys() error {
+	for _, b := range keyBindings {
+		if b.r != 0 && quitKeys.runes[b.r] {
+			return fmt.Errorf("--quit-key %c is already the key to %s", b.r, strings.ToLower(b.help))
+		}
+	}
+	if quitKeys.keys[tcell.KeyLeft] || quitKeys.keys[tcell.KeyRight] {
+		return fmt.Errorf("--quit-key Left and Right already move the hearth")
+	}
+	return nil
+}
+
+var showHelp bool // Whether the help overlay is open
+
+// Columns the hearth moves per key press
+const hearthStep = 2
+
+// moveHearths slides every fire's hearth along the floor
+func moveHearths(dx int) {
+	for _, h := range hearths {
+		h.MoveHearth(dx)
+	}
+}
+
+func toggleHelp() {
+	showHelp = !showHelp
+}
+
+// handleKey applies a key press and reports whether the program should quit
+func handleKey(ev *tcell.EventKey) bool {
+	if ev.Key() == tcell.KeyEscape && showHelp {
+		showHelp = false
+		return false
+	}
+	if quitKeys.matches(ev) {
+		return true
+	}
+
+	switch ev.Key() {
+	case tcell.KeyLeft:
+		moveHearths(-hearthStep)
+	case tcell.KeyRight:
+		moveHearths(hearthStep)
+	case tcell.KeyCtrlZ:
+		// Raw mode keeps the terminal from stopping us itself
+		suspend()
+	case tcell.KeyRune:
+		for _, b := range keyBindings {
+			if b.r == ev.Rune() && b.action != nil {
+				b.action()
+			}
+		}
+	}
+	return false
+}
+
+// drawHelp lists the key bindings in a box in the middle of the screen. The
+// box darkens whatever is under it rather than hiding it completely.
+func drawHelp() {
+	// Lines are measured and drawn in runes, since a key can be labeled with
+	// a character outside ASCII
+	lines := [][]rune{[]rune("Controls"), nil}
+	for _, b := range keyBindings {
+		label, help := b.label, b.help
+		if label == "" {
+			label, help = quitKeys.label(), quitHelp()
+		}
+		lines = append(lines, []rune(fmt.Sprintf("%-12s %s", label, help)))
+	}
+
+	boxW := 0
+	for _, l := range lines {
+		boxW = max(boxW, len(l))
+	}
+	boxW += 4 // Padding and border
+	boxH := len(lines) + 2
+
+	screenW, screenH := screen.Size()
+	left := max((screenW-boxW)/2, 0)
+	areaTop := drawTop()
+	top := areaTop + max((screenH-areaTop-boxH)/2, 0)
+
+	for y := 0; y < boxH; y++ {
+		for x := 0; x < boxW; x++ {
+			char := ' '
+			switch {
+			case (y == 0 || y == boxH-1) && (x == 0 || x == boxW-1):
+				char = '+'
+			case y == 0 || y == boxH-1:
+				char = '-'
+			case x == 0 || x == boxW-1:
+				char = '|'
+			case x >= 2 && x-2 < len(lines[y-1]):
+				char = lines[y-1][x-2]
+			}
+			screen.SetContent(left+x, top+y, char, nil, helpStyle(left+x, top+y))
+		}
+	}
+}
+
+// helpStyle picks a readable style for a help cell, keeping a darkened hint
+// of the fire underneath in color mode
+func helpStyle(x, y int) tcell.Style {
+	if asciiMode {
+		return tcell.StyleDefault
+	}
+
+	_, existing, _ := screen.Get(x, y)
+	_, bg, _ := existing.Decompose()
+	if bg == tcell.ColorDefault {
+		bg = tcell.ColorBlack
+	}
+	r, g, b := bg.RGB()
+	shade := tcell.NewRGBColor(8+r/4, 8+g/4, 8+b/4)
+	return tcell.StyleDefault.Background(shade).Foreground(tcell.NewRGBColor(230, 220, 200))
+}
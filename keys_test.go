@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestDrawHelp draws the help with quit keys labeled in and out of ASCII,
+// with and without Esc, and checks every row of the box is the same width
+// and the quit line reads back as the keys and what they do
+func TestDrawHelp(t *testing.T) {
+	savedScreen, savedKeys := screen, quitKeys
+	t.Cleanup(func() { screen, quitKeys = savedScreen, savedKeys })
+
+	tests := []struct {
+		keys, want string
+	}{
+		{"esc,ctrl-c", "Esc, Ctrl+C  Quit (Esc closes the help first)"},
+		{"q", "q            Quit"},
+		{"ö,ctrl-c", "ö, Ctrl+C    Quit"},
+		{"ø,é,esc", "ø, é, Esc    Quit (Esc closes the help first)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keys, func(t *testing.T) {
+			quitKeys = newKeySet(tt.keys)
+			sim := tcell.NewSimulationScreen("")
+			if err := sim.Init(); err != nil {
+				t.Fatal(err)
+			}
+			defer sim.Fini()
+			screen = sim
+			sim.SetSize(100, 40)
+			drawHelp()
+
+			// Read the box back row by row, from its left border to its right
+			var rows []string
+			for y := range 40 {
+				var row []rune
+				for x := range 100 {
+					r, _, _, _ := sim.GetContent(x, y)
+					row = append(row, r)
+				}
+				if s := strings.TrimSpace(string(row)); s != "" {
+					rows = append(rows, s)
+				}
+			}
+			if len(rows) < 3 {
+				t.Fatalf("the help drew %d rows", len(rows))
+			}
+			width := len([]rune(rows[0]))
+			quit := ""
+			for i, row := range rows {
+				if n := len([]rune(row)); n != width {
+					t.Errorf("row %d of the box is %d wide, the top %d: %q", i, n, width, row)
+				}
+				if strings.Contains(row, "Quit") {
+					quit = strings.TrimSpace(strings.Trim(row, "|"))
+				}
+			}
+			if quit != tt.want {
+				t.Errorf("quit line reads %q, want %q", quit, tt.want)
+			}
+		})
+	}
+}
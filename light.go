@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Formats --light-format can send the fire's color in
+var lightFormats = []string{"rgb", "text", "enttec"}
+
+var lightFormat = "rgb" // How each color is packed for --serial-light
+
+// Colors waiting for the light writer. It holds only the latest, so a slow
+// link drops frames instead of holding up the render loop.
+var lightColors = make(chan tcell.Color, 1)
+
+// startLight opens the light output at target, a serial device path or
+// udp:host:port, and starts writing the colors sent to it
+func startLight(target string) error {
+	var out io.WriteCloser
+	if addr, ok := strings.CutPrefix(target, "udp:"); ok {
+		conn, err := net.Dial("udp", addr)
+		if err != nil {
+			return err
+		}
+		out = conn
+	} else {
+		// The port keeps whatever speed it was set to, e.g. with stty
+		f, err := os.OpenFile(target, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		out = f
+	}
+
+	go writeLight(out)
+	return nil
+}
+
+// writeLight sends each color to out until a write fails, which leaves the
+// fire burning without its light
+func writeLight(out io.WriteCloser) {
+	defer stopOnPanic("light output")
+	defer out.Close()
+
+	for c := range lightColors {
+		if _, err := out.Write(lightPacket(c)); err != nil {
+			logger.Warn("light output stopped", "err", err)
+			return
+		}
+	}
+}
+
+// The light's brightness follows the fire's total heat against its recent
+// average, with the swings exaggerated this many times: a few percent on
+// screen would barely show on a lamp
+const lightFlicker = 4.0
+
+var averageHeat float64 // Total heat, smoothed over the last couple of seconds
+
+// sendLight passes the color of the fires' light to the light writer,
+// replacing any color it hasn't got to yet
+func sendLight() {
+	var r, g, b, n int32
+	heat := 0
+	for _, h := range hearths {
+		cr, cg, cb := h.Glow().RGB()
+		r, g, b, n = r+cr, g+cg, b+cb, n+1
+		heat += h.Heat()
+	}
+	if n == 0 {
+		return
+	}
+
+	if averageHeat == 0 {
+		averageHeat = float64(heat)
+	}
+	averageHeat += (float64(heat) - averageHeat) / 40
+	brightness := 1.0
+	if averageHeat > 0 {
+		brightness = 1 + (float64(heat)/averageHeat-1)*lightFlicker
+	}
+
+	// Dim with the fire as it burns down, too slowly for the average to
+	// cancel it out
+	brightness = min(max(brightness, 0.3), 1.2) * min(max(burnLevel, 0), 1)
+	scale := func(v int32) int32 {
+		return int32(min(float64(v/n)*brightness, 255))
+	}
+
+	select {
+	case <-lightColors:
+	default:
+	}
+	lightColors <- tcell.NewRGBColor(scale(r), scale(g), scale(b))
+}
+
+// lightPacket packs c in the --light-format:
+//   - rgb: three bytes, red, green and blue
+//   - text: "R,G,B" in decimal and a newline
+//   - enttec: an Enttec DMX USB Pro "send DMX" message with the color on
+//     channels 1 to 3
+func lightPacket(c tcell.Color) []byte {
+	r, g, b := c.RGB()
+	switch lightFormat {
+	case "text":
+		return fmt.Appendf(nil, "%d,%d,%d\n", r, g, b)
+	case "enttec":
+		// Start of message, label 6, data length (start code and three
+		// channels) low byte first, the DMX start code, then end of message
+		return []byte{0x7E, 6, 4, 0, 0, byte(r), byte(g), byte(b), 0xE7}
+	}
+	return []byte{byte(r), byte(g), byte(b)}
+}
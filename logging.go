@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Diagnostics for --verbose and --log-file. Without --log-file the messages
+// are held until the terminal is restored and then written to stderr, where
+// anything written mid-frame would land in the middle of the fire.
+var logger = slog.New(slog.DiscardHandler)
+
+var (
+	heldLogMu sync.Mutex
+	heldLog   bytes.Buffer // Messages waiting for flushLog
+)
+
+// heldWriter appends to heldLog
+type heldWriter struct{}
+
+func (heldWriter) Write(p []byte) (int, error) {
+	heldLogMu.Lock()
+	defer heldLogMu.Unlock()
+	return heldLog.Write(p)
+}
+
+// startLogging points logger at the log file at path, or at the held
+// messages for stderr if there's no path but verbose is set
+func startLogging(verbose bool, path string) error {
+	var w io.Writer
+	switch {
+	case path != "":
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		w = f
+	case verbose:
+		w = heldWriter{}
+	default:
+		return nil
+	}
+
+	logger = slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return nil
+}
+
+// flushLog writes out the held messages, once the terminal is back to normal
+func flushLog() {
+	heldLogMu.Lock()
+	defer heldLogMu.Unlock()
+	os.Stderr.Write(heldLog.Bytes())
+	heldLog.Reset()
+}
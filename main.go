@@ -1,666 +1,1385 @@
 package main
 
 import (
-	"bytes"
+	"encoding/binary"
 	"flag"
+	"fmt"
 	"math"
 	"math/rand"
-	"sort"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/donnybeelo/fireplace/fireplace"
 	"github.com/gdamore/tcell/v2"
 	"github.com/hajimehoshi/oto/v2"
 )
 
 var (
-	width       int
-	height      int // Terminal height
-	fireHeight  int // Simulation height (height * 2 + seed)
-	hearthLeft  int // Left boundary of the fireplace
-	hearthRight int // Right boundary of the fireplace
 	screen      tcell.Screen
-	fire        []int
-	woodMap     []int // Stores log ID for each pixel (0 = empty)
+	hearths     []*hearth // Independent fires, one per screen region
 	colors      []tcell.Color
-	logCount    int // Number of logs generated
 	tick        int // Frame counter for animations
 	audioCtx    *oto.Context
-	rumbleState float64 // State for brown noise rumble
 	silentMode  bool    // Whether audio is disabled
+	flareMode   bool    // Whether big crackles flash the fire
+	flareBoost  int     // Extra heat shown for the current frame only
+	paletteName string  // Built-in palette to draw the fire with
+	temperature float64 // Palette color temperature, -1 (cool) to 1 (warm)
+	logsWanted  int     // Fixed number of logs to generate (0 = scale with width)
+	noLogsMode  bool    // Whether to burn from the floor with no logs
+	noFlatten   bool    // Whether the top logs keep their random angles
+	splitMode   bool    // Whether to run two fires side by side
+	ambientMode bool    // Whether to light the room with a dim gradient
+	asciiMode   bool    // Whether to draw with plain characters and no color
+	smoothMode  bool    // Whether to blur heat vertically when drawing
+	ditherMode  bool    // Whether to ordered-dither the fire's colors
+	direction   string  // Which way the fire burns: "up" or "down"
+	emberFloor  int     // Least heat shown over the log bed (0 = off)
+	floorLine   bool    // Whether to draw a row of ash along the floor
+	wrapMode    bool    // Whether drifting flames wrap around the sides
+	noTopClear  bool    // Whether to stop clearing the top row each tick
+	blendCap    = 0.85  // Most a flame covers what's behind it, from above 0 to 1
+	emberRate   float64 // Embers thrown off per tick, on average (0 = none)
+	emberSpeed  = 0.5   // Sub-pixel rows an ember rises per tick
+	emberLife   = 40    // Most ticks an ember glows for
+	hazeMode    bool    // Whether the air above the flames shimmers
+	consumeMode bool    // Whether the logs burn away over time
+	seed        int64   // Seed every random generator is derived from
+	glyphRamp   string  // Characters to texture the flames with ("" = half blocks)
+	subcellMode bool    // Whether flame tips are drawn with eighth blocks
+	vignette    float64 // How much the frame darkens towards its corners, from 0 to 1
+	coalsMode   bool    // Whether to show only a low bed of glowing coals
+	colorMode   string  // "auto" or "truecolor" (which skips the color checks)
+
+	// Shape parameters, set by presets such as --campfire
+	logLayout     = "hearth" // How logs are arranged: "hearth" or "teepee"
+	hearthWidth   = 0        // Columns the hearth spans (0 = whole region)
+	fireSpanRatio = 0.8      // Fraction of the log span that gets refueled
+	lickChance    = 0.2      // Chance per cell of a flame lick carrying higher
 )
 
-// Doom fire palette definition (RGB) - No white/yellow
-var palette = []uint32{
-	0x070707, 0x1F0707, 0x2F0F07, 0x470F07, 0x571707, 0x671F07, 0x771F07, 0x8F2707,
-	0x9F2F07, 0xAF3F07, 0xBF4707, 0xC74707, 0xDF4F07, 0xDF5707, 0xDF5707, 0xD75F07,
-	0xD75F07, 0xD7670F, 0xCF6F0F, 0xCF770F, 0xCF7F0F, 0xCF8717, 0xC78717, 0xC78F17,
-	0xC7971F, 0xBF9F1F, 0xBF9F1F, 0xBFA727, 0xBFA727, 0xBFAF2F, 0xB7B72F, 0xB7B737,
-	0xAF3F07, 0xAF3F07, 0xAF3F07, 0xAF3F07,
+// How chaotically the flames move, from 0 (laminar) to 2 (wild)
+var turbulence = 1.0
+
+// How strongly the flames lean toward hotter columns, from 0 to 1
+var convection float64
+
+// A chimney's draft for --chimney
+var (
+	chimneyMode bool  // Whether the upper flames and embers are drawn in toward a flue
+	flueX       = 0.5 // Where the flue is across the fire, from 0 to 1
+)
+
+// How sharply the flames die away toward the sides, 6 by default
+var edgeFalloff = 6.0
+
+// How tall the flames burn relative to the standard fire, and the bounds
+// for the live keys that adjust it
+var flameHeight = 1.0
+
+const (
+	minFlameHeight = 0.5
+	maxFlameHeight = 2.5
+)
+
+// How bright the logs are apart from the fire's glow, and the bounds for the
+// live keys that adjust it
+var woodLight = 1.0
+
+const (
+	minWoodLight = 0.25
+	maxWoodLight = 4.0
+)
+
+// Refuel tuning
+var (
+	heatSources = 3    // Heat injections per refueled column each tick
+	refuelDepth = 0.75 // Fraction of the wood's height heat is injected into
+	maxHeat     = 36   // Heat injected by refueling, at most 36
+)
+
+// How much fuel the fire still gets, from 1 (full) down to 0 (out)
+var burnLevel = 1.0
+
+// Fuel scale set with --intensity or over the control socket, up to
+// maxIntensity
+var intensity = 1.0
+
+const maxIntensity = 2.0
+
+// stoke flashes the fire brighter for a frame, as a loud crack does
+func stoke() {
+	flareBoost = 6
+}
+
+// addLog throws a log onto every fire, which lands with a crack
+func addLog() {
+	added := false
+	for _, h := range hearths {
+		added = h.AddLog() || added
+	}
+	if !added {
+		return
+	}
+
+	stoke()
+	if audioCtx != nil {
+		playWoodCrack(rand.New(rand.NewSource(seed+int64(tick))), 0.15, 0.35*audioLevel(), crackSpread)
+	}
 }
 
-func init() {
-	colors = make([]tcell.Color, 37) // 0 to 36
-	// Fill 0 with black
-	colors[0] = tcell.NewRGBColor(0, 0, 0)
+// Bark texture on the logs, from 0 (smooth) to 2 (gnarled)
+var texture = 1.0
 
-	for i, hex := range palette {
-		if i+1 >= len(colors) {
-			break
+// Scale of the room hearth logs leave between each other
+var logSpacing = 1.0
+
+// The logs the hearth is built around, placed first near its middle
+var (
+	coreLogs   = 4   // How many there are
+	coreSpread = 5.0 // Columns either side of the middle they're kept within
+)
+
+// Breathing for --breathe
+var (
+	breatheMode  bool  // Whether the fire slowly swells and subsides
+	breatheDepth = 0.3 // How far it subsides, from 0 to 1
+)
+
+// Half block the flames are drawn with: "upper" or "lower"
+var blockChar string
+
+// Tint of the logs, one of fireplace.WoodColors
+var woodColor string
+
+// How much the logs' red, green and blue rise per unit of heat around them
+var logGlow = glowFlag{5, 2, 0}
+
+// Most any channel of --log-glow can rise per unit of heat
+const maxLogGlow = 15
+
+// How refueling thins out toward the sides, one of fireplace.FuelProfiles
+var fuelProfile string
+
+// Hue cycling for --enchanted
+var (
+	enchantedMode bool   // Whether the palette cycles through the spectrum
+	cycleSpeed    = 30.0 // Degrees around the hue wheel per second
+)
+
+// Color-blind-friendly palette: a blue to pale-yellow ramp that stays on the
+// blue/yellow axis most color vision deficiencies preserve, with luminance
+// rising at every step so hotter always reads brighter
+var cbPalette = []uint32{
+	0x070718, 0x090B25, 0x0A0E31, 0x0C123E, 0x0E154A, 0x101957, 0x121F63, 0x142670,
+	0x162C7C, 0x183388, 0x1A3994, 0x1C40A1, 0x1E46AD, 0x224FB5, 0x2859BC, 0x2E63C3,
+	0x336CCA, 0x3976D1, 0x3F80D7, 0x458ADE, 0x4F92E2, 0x5B9BE4, 0x68A4E6, 0x74ACE9,
+	0x80B5EB, 0x8DBDED, 0x99C6EF, 0xA6CDED, 0xB3D3E8, 0xC0D9E3, 0xCDDFDF, 0xDAE5DA,
+	0xE3EAD8, 0xEAEDD8, 0xF1F1D8, 0xF8F4D8,
+}
+
+// Built-in palettes selectable with --palette
+var palettes = []struct {
+	name   string
+	colors []uint32
+}{
+	{"doom", fireplace.DoomPalette},
+	{"cb", cbPalette},
+	{"daylight", daylightPalette},
+}
+
+// lookupPalette finds a built-in palette by name, or the colors read from
+// --palette-file
+func lookupPalette(name string) ([]uint32, bool) {
+	if name == filePaletteName && filePalette != nil {
+		return filePalette, true
+	}
+	for _, p := range palettes {
+		if p.name == name {
+			return p.colors, true
 		}
-		r := int32((hex >> 16) & 0xFF)
-		g := int32((hex >> 8) & 0xFF)
-		b := int32(hex & 0xFF)
-		colors[i+1] = tcell.NewRGBColor(r, g, b)
 	}
+	return nil, false
+}
+
+// loadPalette rebuilds colors from the palette definition. The new slice is
+// swapped in whole so a reload never leaves colors partially filled.
+func loadPalette() {
+	colors = buildPalette(paletteName)
+	fadeTo = nil
+}
+
+// buildPalette expands a named palette into the 37 heat colors
+func buildPalette(name string) []tcell.Color {
+	palette, ok := lookupPalette(name)
+	if !ok {
+		palette = fireplace.DoomPalette
+	}
+
+	c := fireplace.NewPalette(palette)
+	for i := 1; i < len(c); i++ {
+		c[i] = tcell.NewRGBColor(applyTemperature(c[i].RGB()))
+	}
+	return c
+}
+
+// Frames a palette crossfade takes (about a second at 20 FPS)
+const paletteFadeFrames = 20
+
+var (
+	fadeFrom  []tcell.Color // Colors at the start of the crossfade
+	fadeTo    []tcell.Color // Palette being faded to (nil when not fading)
+	fadeFrame int           // Frames into the current crossfade
+)
+
+// cyclePalette starts a crossfade to the next built-in palette. Starting
+// from the colors on screen means cycling mid-fade doesn't jump.
+func cyclePalette() {
+	next := 0
+	for i, p := range palettes {
+		if p.name == paletteName {
+			next = (i + 1) % len(palettes)
+		}
+	}
+	fadeToPalette(palettes[next].name)
+}
+
+// fadeToPalette starts a crossfade to the named palette
+func fadeToPalette(name string) {
+	paletteName = name
+	notify("palette: " + name)
+	fadeFrom = colors
+	fadeTo = buildPalette(paletteName)
+	fadeFrame = 0
+}
+
+// stepPaletteFade advances the crossfade by a frame, blending every heat
+// color between the old and new palettes
+func stepPaletteFade() {
+	if fadeTo == nil {
+		return
+	}
+
+	fadeFrame++
+	if fadeFrame >= paletteFadeFrames {
+		colors = fadeTo
+		fadeTo = nil
+		return
+	}
+
+	t := float64(fadeFrame) / paletteFadeFrames
+	c := make([]tcell.Color, len(fadeTo))
+	for i := range c {
+		c[i] = mixColors(fadeFrom[i], fadeTo[i], t)
+	}
+	colors = c
+}
+
+// firePalette returns the colors to draw this frame with: the palette, turned
+// around the hue wheel as far as --enchanted has cycled it by now
+func firePalette() []tcell.Color {
+	if !enchantedMode {
+		return colors
+	}
+
+	// tick counts simulation steps, 20 a second at the normal time scale
+	angle := float64(tick) / 20 * cycleSpeed * math.Pi / 180
+	c := make([]tcell.Color, len(colors))
+	for i := range c {
+		r, g, b := colors[i].RGB()
+		c[i] = tcell.NewRGBColor(rotateHue(r, g, b, angle))
+	}
+	return c
+}
+
+// rotateHue turns a color around the hue wheel by angle radians while
+// keeping its luminance, like the CSS hue-rotate filter. Black stays black.
+func rotateHue(r, g, b int32, angle float64) (int32, int32, int32) {
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	fr, fg, fb := float64(r), float64(g), float64(b)
+
+	nr := (0.213+cos*0.787-sin*0.213)*fr + (0.715-cos*0.715-sin*0.715)*fg + (0.072-cos*0.072+sin*0.928)*fb
+	ng := (0.213-cos*0.213+sin*0.143)*fr + (0.715+cos*0.285+sin*0.140)*fg + (0.072-cos*0.072-sin*0.283)*fb
+	nb := (0.213-cos*0.213-sin*0.787)*fr + (0.715-cos*0.715+sin*0.715)*fg + (0.072+cos*0.928+sin*0.072)*fb
+
+	channel := func(v float64) int32 { return int32(math.Max(0, math.Min(255, v))) }
+	return channel(nr), channel(ng), channel(nb)
+}
+
+// applyTemperature shifts a palette color warmer or cooler. Warming pushes
+// red up and blue down; cooling pulls red down and blue up towards it, which
+// turns the fire a ghostly blue-white.
+func applyTemperature(r, g, b int32) (int32, int32, int32) {
+	t := math.Max(-1, math.Min(1, temperature))
+	fr, fb := float64(r), float64(b)
+
+	if t > 0 {
+		fr += (255 - fr) * 0.3 * t
+		fb *= 1 - 0.6*t
+	} else if t < 0 {
+		if fr > fb {
+			fb += (fr - fb) * 0.8 * -t
+		}
+		fr *= 1 + 0.4*t
+	}
+
+	return int32(min(fr, 255)), g, int32(max(fb, 0))
 }
 
 func main() {
 	// Parse command line flags
 	silent := flag.Bool("silent", false, "start with audio disabled")
+	flag.Float64Var(&volume, "volume", volume, "how loud the fire is, from 0 (silent) to 1 (full)")
+	flag.BoolVar(&pauseAudio, "pause-audio", pauseAudio, "silence the crackling while the fire is paused with Space; m mutes it on its own either way")
 	flag.BoolVar(silent, "s", false, "start with audio disabled (shorthand)")
+	flag.Int64Var(&seed, "seed", 0, "seed for the logs, flames and audio (0 picks one from the clock)")
+	flag.StringVar(&themeName, "theme", "", "apply a bundle of settings: cozy-cabin, blue-hell, dying-embers or roaring-bonfire")
+	flag.StringVar(&paletteName, "palette", "doom", "fire palette: doom, cb for a color-blind-friendly blue to white ramp, or daylight for light backgrounds")
+	flag.BoolVar(&safeMode, "safe", false, "the most compatible setup for an unknown terminal: implies --ascii and --silent, and skips --adapt-bg, the window title and the clipboard")
+	flag.BoolVar(&adaptBg, "adapt-bg", false, "ask the terminal for its background color and pick a palette that suits a light one")
+	flag.StringVar(&paletteFile, "palette-file", "", "draw the fire with the hex colors listed in this file, coldest first")
+	flag.BoolVar(&watchPalette, "watch", false, "reload --palette-file whenever it changes")
+	flag.StringVar(&dumpPalette, "dump-palette", "", "file the p key saves the colors on screen to, for --palette-file (default a new timestamped file each time)")
+	flag.BoolVar(&enchantedMode, "enchanted", false, "slowly cycle the fire's colors through the spectrum")
+	flag.Float64Var(&cycleSpeed, "cycle-speed", cycleSpeed, "degrees around the hue wheel per second that --enchanted cycles (negative reverses)")
+	flag.Float64Var(&temperature, "temperature", 0, "shift the palette warmer (up to 1) or cooler (down to -1)")
+	flag.IntVar(&logsWanted, "logs", 0, fmt.Sprintf("number of logs to stack, up to %d (0 scales with the width)", fireplace.MaxLogs))
+	flag.BoolVar(&stormMode, "storm", false, "send gusts of wind sweeping across the fire now and then, louder in the rumble")
+	flag.Float64Var(&stormIntensity, "storm-intensity", stormIntensity, "how violent --storm's gusts are, from 0 (a breeze) through 1 to 2 (a gale)")
+	flag.Float64Var(&baseWind, "wind", 0, fmt.Sprintf("bend the flames in a steady wind, from %g to the left to %g to the right, which --storm's gusts blow on top of", -maxWind, maxWind))
+	flag.BoolVar(&noLogsMode, "no-logs", false, "hide the logs and let the flames rise straight from the floor")
+	flag.BoolVar(&noFlatten, "no-flatten", false, "leave the logs on top of the pile at their random angles, tossed in a heap, instead of laying them flat")
+	flag.Float64Var(&logSpacing, "log-spacing", logSpacing, "how far apart hearth logs are placed: below 1 packs them tighter, above 1 spreads them out")
+	flag.IntVar(&coreLogs, "core-logs", coreLogs, "hearth logs placed first, near the middle, where the fire burns hottest")
+	flag.StringVar(&logLayout, "layout", logLayout, "how the logs are stacked: hearth, a pile along the floor, or teepee, a cone of sticks")
+	flag.Float64Var(&fireSpanRatio, "fire-span", fireSpanRatio, "fraction of the log bed that burns, from the middle out, above 0 and at most 1")
+	flag.Float64Var(&coreSpread, "core-spread", coreSpread, "columns either side of the middle the --core-logs are placed within: smaller packs the core tighter")
+	flag.IntVar(&heatSources, "heat-sources", heatSources, "heat injections per burning column each tick")
+	flag.Float64Var(&refuelDepth, "refuel-depth", refuelDepth, "fraction of the wood's height, from the floor up, that flames start in: low burns from the base, 1 from the whole bundle")
+	flag.IntVar(&maxHeat, "max-heat", maxHeat, "heat injected into burning columns, from 1 to 36")
+	flag.Float64Var(&intensity, "intensity", intensity, fmt.Sprintf("scale how much fuel the fire gets, from 0 (none) through 1 to %g", maxIntensity))
+	flag.Float64Var(&timeScale, "time-scale", timeScale, "simulation speed relative to the frame rate, e.g. 0.5 for slow motion")
+	flag.BoolVar(&flareMode, "flare", false, "flash the fire brighter on loud crackles")
+	flag.BoolVar(&visualizerMode, "visualizer", false, "warm and brighten the fire's colors for a moment on every loud crackle")
+	flag.Float64Var(&visualizerStrength, "visualizer-strength", visualizerStrength, "how far --visualizer's loudest crackles push the colors, from 0 to 1")
+	campfire := flag.Bool("campfire", false, "burn a small conical campfire in the middle of the screen")
+	flag.BoolVar(&asciiMode, "ascii", false, "draw the fire with plain characters and no color")
+	flag.StringVar(&colorMode, "colors", "auto", "color output: auto (plain characters on limited terminals or with NO_COLOR) or truecolor")
+	flag.Float64Var(&woodLight, "wood-brightness", woodLight, fmt.Sprintf("brightness of the logs apart from the fire's glow, from %g to %g; [ and ] adjust it live", minWoodLight, maxWoodLight))
+	flag.Float64Var(&vignette, "vignette", 0, "darken the frame towards its corners by this much, from 0 (off) to 1, to draw the eye to the fire")
+	flag.Float64Var(&blendCap, "saturation-cap", blendCap, "how fully the hottest flames show their palette color over what's behind them, from above 0 (muted) to 1 (intense)")
+	flag.BoolVar(&ditherMode, "dither", false, "dither the fire's colors to hide banding on 256-color terminals")
+	flag.IntVar(&emberFloor, "floor", 0, "least heat shown over the log bed so embers always glow, from 4 (faint) to 36 (0 = off)")
+	flag.BoolVar(&floorLine, "floor-line", false, "draw a row of ash along the floor for the fire to sit on")
+	flag.BoolVar(&breatheMode, "breathe", false, "let the fire gently swell and subside every 8 seconds")
+	flag.Float64Var(&breatheDepth, "breathe-depth", breatheDepth, "how far --breathe lets the fire subside, from 0 to 1")
+	flag.Float64Var(&turbulence, "turbulence", turbulence, "how much the flames waver, from 0 (steady, like gas) through 1 to 2 (wild)")
+	flag.BoolVar(&chimneyMode, "chimney", false, "draw the upper flames and embers in toward a flue above the fire, so it tapers as it rises")
+	flag.Float64Var(&flueX, "flue-x", flueX, "where --chimney's flue is across the fire, from 0 (left edge) through 0.5 to 1 (right edge)")
+	flag.Float64Var(&convection, "convection", 0, "how strongly flames lean toward hotter columns and gather into a core, from 0 (drift at random) to 1")
+	flag.BoolVar(&noTopClear, "no-top-clear", false, "let the tallest flames reach the top row, at the cost of the odd cell of heat hanging there")
+	flag.Float64Var(&emberRate, "ember-rate", 0, "embers the flames throw off each tick, on average, e.g. 0.1 for a few lazy sparks or 3 for a shower (0 = none)")
+	flag.Float64Var(&emberSpeed, "ember-speed", emberSpeed, "how fast embers rise, in half-rows per tick")
+	flag.IntVar(&emberLife, "ember-life", emberLife, "most ticks an ember glows for before it goes out")
+	flag.BoolVar(&hazeMode, "haze", false, "make the air just above the flames waver as if seen through rising heat")
+	flag.BoolVar(&wrapMode, "wrap", false, "let flames drifting off one side of the fire come back in on the other instead of piling up")
+	flag.Float64Var(&edgeFalloff, "edge-falloff", edgeFalloff, "how sharply flames die away toward the sides: 6 is the standard cutoff, lower tapers gently (0 = none)")
+	flag.Float64Var(&texture, "texture", texture, "bark texture on the logs, from 0 (smooth) through 1 to 2 (gnarled)")
+	flag.BoolVar(&coalsMode, "coals", false, "burn down to a low, gently pulsing bed of coals with sparse crackles")
+	flag.BoolVar(&consumeMode, "consume", false, "burn the logs away over time, then let the fire die out and exit")
+	flag.StringVar(&direction, "direction", "up", "which way the fire burns: up, or down from logs on the ceiling")
+	flag.StringVar(&fuelProfile, "fuel-profile", "linear", "how the flames spread across the bed: linear (thinning toward the sides), gaussian (a tall middle) or flat (an even wall)")
+	flag.Var(&logGlow, "log-glow", "how much the logs' red, green and blue brighten per unit of heat around them: e.g. 6,0.5,0 for deep red, 7,4,0.5 for bright orange or 1,0.4,0 to barely glow")
+	flag.StringVar(&woodColor, "wood-color", "oak", "tint of the logs: oak (dark brown), ash (grey), ember (glowing orange) or birch (pale)")
+	flag.StringVar(&blockChar, "block", "upper", "half block to draw with: upper (▀) or lower (▄), whichever the terminal shows without gaps")
+	flag.StringVar(&glyphRamp, "glyph-ramp", "", "texture the flames with these characters for increasing heat, e.g. \" ░▒▓█\"")
+	flag.BoolVar(&subcellMode, "subcell", false, "draw the flame tips with eighth blocks (▁ to ▇) for finer heights than half blocks; --glyph-ramp takes precedence")
+	flag.BoolVar(&adaptiveMode, "adaptive", false, "save power by dropping to 5 FPS after 30s without input or when output isn't a terminal")
+	flag.BoolVar(&clockMode, "clock", false, "show the time in large digits over the fire")
+	flag.BoolVar(&clockDate, "clock-date", false, "show the date under the --clock")
+	flag.StringVar(&clockPosition, "clock-position", "top", "where the --clock goes: top, center or bottom")
+	flag.BoolVar(&smoothMode, "smooth", false, "soften flicker by blurring heat between sub-pixel rows when drawing")
+	flag.BoolVar(&ambientMode, "ambient", false, "light the background with a dim glow from the hearth")
+	flag.BoolVar(&splitMode, "split", false, "run two independent fires side by side")
+	flag.Var(&quitKeys, "quit-key", "keys that quit, as a comma-separated list such as q,esc,ctrl-c")
+	flag.IntVar(&inlineRows, "inline", 0, "burn in this many rows at the bottom of the terminal's main screen, keeping the scrollback above, instead of taking over the whole screen")
+	flag.Var(&regionSize, "size", "render into a WxH region instead of the full screen")
+	flag.Var(&regionOrigin, "origin", "top-left X,Y of the --size region")
+	flag.IntVar(&reflectionRows, "reflection", 0, "mirror the flames faintly in a shiny floor this many rows deep under the fire")
+	flag.IntVar(&gridScale, "grid-scale", gridScale, "simulate the fire at 1/N of the screen's resolution, drawing each cell as an NxN block: chunkier but cheaper on big terminals")
+	flag.IntVar(&bottomMargin, "bottom-margin", 0, "leave this many rows empty under the fire, at the bottom of the screen or --size region")
+	once := flag.Bool("once", false, "draw a single still frame, then exit on any key (printed as text when stdout isn't a terminal)")
+	flag.IntVar(&warmupTicks, "warmup", warmupTicks, "ticks to simulate before a new or resized fire is first drawn")
+	dumpPath := flag.String("dump-state", "", "write the generated logs as JSON to this file")
+	sceneName := flag.String("scene", "", "bring back the logs and settings saved with --save-scene under this name")
+	saveName := flag.String("save-scene", "", "on exit, save the logs and settings under this name for --scene")
+	maskPath := flag.String("mask", "", "burn the dark shapes of this PNG, GIF or JPEG image instead of logs")
+	micPath := flag.String("mic", "", "make the fire follow the loudness of raw signed 16-bit little-endian PCM read from this file, or - for stdin; nothing is captured from a microphone itself, so pipe one in (e.g. arecord -f S16_LE | fireplace --mic -)")
+	demo := flag.Bool("demo", false, "show off the fire's features one after another until a key is pressed")
+	controlPath := flag.String("control-socket", "", "accept commands such as stoke, mute and quit on a Unix socket at this path")
+	lightTarget := flag.String("serial-light", "", "stream the fire's color each frame to a light on this serial device, or udp:host:port")
+	flag.StringVar(&lightFormat, "light-format", lightFormat, "how --serial-light packs each color: rgb (3 bytes), text (R,G,B lines) or enttec (DMX USB Pro, channels 1-3)")
+	eventsOut := flag.String("events-out", "", "write a line of JSON for each crack, sizzle, flare, gust and burned-out log to this file, or fd:N")
+	metricsAddr := flag.String("metrics", "", "serve runtime stats as JSON over HTTP on this address, e.g. localhost:9090")
+	duration := flag.Duration("duration", 0, "exit after running this long, e.g. 30m (0 runs forever)")
+	sleep := flag.Duration("sleep", 0, "let the fire burn down and the sound fade out over this long, then exit")
+	flag.IntVar(&sampleRate, "sample-rate", sampleRate, "audio sample rate in Hz, e.g. 48000")
+	flag.Float64Var(&rumbleWidth, "rumble-width", 0, "stereo width of the rumble, from 0 (mono) to 1 (wide)")
+	flag.StringVar(&rumbleQuality, "rumble-quality", rumbleQuality, "high, or low to work out only every 4th rumble sample and save CPU")
+	flag.Float64Var(&baseCrackles, "crackle-rate", baseCrackles, fmt.Sprintf("how often the wood cracks, from 0 (never) through 1 to %g", maxCrackles))
+	flag.Float64Var(&crackleJitter, "crackle-jitter", crackleJitter, "how irregular the gaps between crackles are, from 0 (evenly spaced) to 1 (fully random)")
+	flag.Float64Var(&crackTone, "crack-tone", crackTone, "brightness of the wood cracks, from 0.25 (deep pops) through 1 to 4 (sharp snaps)")
+	flag.Float64Var(&crackDecay, "crack-decay", crackDecay, "how fast each wood crack dies away, from 2 (ringing) through 12 to 40 (snappy)")
+	flag.Float64Var(&crackSpread, "crack-spread", 0, "how wide each crack sounds, from 0 (a point) to 1 (independent noise in each ear)")
+	flag.StringVar(&configPath, "config", defaultConfigPath(), "path to a config file of name = value settings")
+	listPalettes := flag.Bool("list-palettes", false, "print the built-in palette names and exit")
+	printVersion := flag.Bool("version", false, "print the version, commit and build date and exit")
+	flag.StringVar(&pngDir, "png-dir", "", "render frames off screen at the headless size and write them to this directory as PNGs, then exit")
+	flag.IntVar(&exportFrames, "frames", exportFrames, "number of frames --png-dir writes")
+	flag.Var(&cellSize, "cell", "WxH pixels per character in --png-dir frames")
+	benchFrames := flag.Int("bench-frames", 0, "time this many frames drawn off screen at the headless size, then exit")
+	verbose := flag.Bool("verbose", false, "log what was chosen at startup and anything that went wrong, to stderr on exit or to --log-file")
+	logPath := flag.String("log-file", "", "append the --verbose log to this file as it's written")
+	memProfile := flag.String("memprofile", "", "write a heap profile to this file on exit, for tracking down memory growth")
+	flag.Usage = printUsage
 	flag.Parse()
+
+	if *printVersion {
+		fmt.Println(versionString())
+		return
+	}
+
+	if *listPalettes {
+		for _, p := range palettes {
+			fmt.Println(p.name)
+		}
+		return
+	}
+
+	if err := startLogging(*verbose, *logPath); err != nil {
+		fmt.Fprintln(os.Stderr, "--log-file:", err)
+		os.Exit(1)
+	}
+	logger.Info("build", "version", versionString())
+
+	if *memProfile != "" {
+		defer writeMemProfile(*memProfile)
+	}
+
+	cliFlags = map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { cliFlags[f.Name] = true })
+
+	// A scene's settings count as given on the command line
+	if *sceneName != "" {
+		if err := loadScene(*sceneName); err != nil {
+			fmt.Fprintln(os.Stderr, "--scene:", err)
+			os.Exit(1)
+		}
+	}
+	if *saveName != "" {
+		if _, err := scenePath(*saveName); err != nil {
+			fmt.Fprintln(os.Stderr, "--save-scene:", err)
+			os.Exit(1)
+		}
+		defer func() {
+			if err := saveScene(*saveName); err != nil {
+				fmt.Fprintln(os.Stderr, "--save-scene:", err)
+			}
+		}()
+	}
+
+	// Settings from the config file fill in anything not given as a flag
+	if configPath != "" {
+		if err := loadConfig(configPath, cliFlags["config"]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if err := applyTheme(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := validateSettings(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := loadPaletteFile(); err != nil {
+		fmt.Fprintln(os.Stderr, "--palette-file:", err)
+		os.Exit(1)
+	}
 	silentMode = *silent
+	noteSceneSettings()
+	applySafeMode()
 
-	var err error
-	screen, err = tcell.NewScreen()
-	if err != nil {
-		panic(err)
+	// Honor the NO_COLOR convention unless true color was asked for
+	if os.Getenv("NO_COLOR") != "" && colorMode != "truecolor" {
+		asciiMode = true
+	}
+
+	if *campfire {
+		useCampfire()
 	}
+	applyConfig()
 
-	if err := screen.Init(); err != nil {
-		panic(err)
+	if seed == 0 {
+		seed = time.Now().UnixNano()
 	}
+
+	// Without a usable mask the logs are stacked as usual
+	if *maskPath != "" {
+		if err := loadMask(*maskPath); err != nil {
+			fmt.Fprintln(os.Stderr, "--mask:", err)
+			logger.Warn("mask not loaded", "path", *maskPath, "err", err)
+		}
+	}
+
+	// Without usable input the fire just burns as usual
+	if *micPath != "" {
+		if err := startMic(*micPath); err != nil {
+			fmt.Fprintln(os.Stderr, "--mic:", err)
+			logger.Warn("mic not started", "path", *micPath, "err", err)
+		}
+	}
+
+	if *lightTarget != "" {
+		if err := startLight(*lightTarget); err != nil {
+			fmt.Fprintln(os.Stderr, "--serial-light:", err)
+			os.Exit(1)
+		}
+	}
+	if *eventsOut != "" {
+		if err := startEvents(*eventsOut); err != nil {
+			fmt.Fprintln(os.Stderr, "--events-out:", err)
+			os.Exit(1)
+		}
+	}
+	if *metricsAddr != "" {
+		if err := startMetrics(*metricsAddr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *controlPath != "" {
+		ln, err := startControl(*controlPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer ln.Close()
+	}
+
+	// A still frame with nowhere to show it is rendered off screen at the
+	// size from COLUMNS and LINES, and printed as plain characters. Benchmark
+	// and exported frames are drawn off screen in full color.
+	benchmark := *benchFrames > 0
+	exporting := pngDir != ""
+	headless := benchmark || exporting || *once && !isTerminal(os.Stdout)
+
+	var err error
+	if headless {
+		switch {
+		case exporting:
+			// Images have only the colors to show
+			asciiMode = false
+		case !benchmark:
+			asciiMode = true
+		}
+		screen, err = newHeadlessScreen()
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		// The terminal has to be asked before tcell takes over its input
+		if adaptBg {
+			adaptToBackground()
+		}
+
+		defer prepareTerminal()()
+		screen, err = tcell.NewScreen()
+		if err != nil {
+			panic(err)
+		}
+
+		if err := screen.Init(); err != nil {
+			panic(err)
+		}
+		if !safeMode {
+			screen.SetTitle("🔥 fireplace")
+		}
+	}
+	// Deferred in reverse: a panic restores the terminal first, and anything
+	// that failed along the way is reported last
+	defer flushLog()
+	defer reportFailures()
 	defer screen.Fini()
+	defer blankScreen()
+	defer crashGuard()
 
-	screen.SetStyle(tcell.StyleDefault.Background(tcell.ColorDefault).Foreground(tcell.ColorBlack))
-	screen.Clear()
+	// Terminals with fewer than 256 colors would mangle the palette, so
+	// fall back to plain characters unless true color was asked for
+	if colorMode == "auto" && screen.Colors() < 256 {
+		asciiMode = true
+	}
+	screenW, screenH := screen.Size()
+	logger.Info("screen", "width", screenW, "height", screenH, "colors", screen.Colors(), "ascii", asciiMode)
+	logger.Info("settings", "palette", paletteName, "seed", seed, "theme", themeName, "fps", 1/frameTime.Seconds(),
+		"adaptive", adaptiveMode, "intensity", intensity, "time_scale", timeScale)
+
+	clearScreen(tcell.StyleDefault.Background(tcell.ColorDefault).Foreground(tcell.ColorBlack))
 
 	// Initial setup
-	rand.New(rand.NewSource(time.Now().UnixNano()))
 	resize()
+	if *dumpPath != "" {
+		if err := dumpState(*dumpPath); err != nil {
+			screen.Fini()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	// Neither a benchmark, an export nor a still frame needs an animation
+	// loop or audio
+	if benchmark {
+		runBench(*benchFrames)
+		return
+	}
+	if exporting {
+		if err := exportPNGs(); err != nil {
+			screen.Fini()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if headless {
+		if err := printStill(); err != nil {
+			screen.Fini()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *once {
+		showStill()
+		return
+	}
 
 	// Initialize audio
 	if !silentMode {
 		initAudio()
+		if audioCtx != nil {
+			logger.Info("audio started", "sample_rate", audioRate)
+		}
+		// initAudio only tries once, so the hint is only ever shown once
+		switch {
+		case audioErr != nil && *verbose:
+			showStatus("audio unavailable: " + audioErr.Error())
+		case audioErr != nil:
+			showStatus("audio unavailable, see --verbose for why")
+		}
+
+		// Each audio goroutine gets its own generator derived from the seed,
+		// since *rand.Rand isn't safe for concurrent use
+
+		// Start the output stream with its continuous low-frequency rumble,
+		// then the crackling mixed into it
+		startMixer(rand.New(rand.NewSource(seed + 2)))
+		go audioLoop(rand.New(rand.NewSource(seed+1)), coalsMode, crackSpread)
+	}
 
-		// Start audio crackling in background
-		go audioLoop()
+	// Without sound there are no cracks to follow, so flare on our own schedule
+	if (flareMode || visualizerMode) && audioCtx == nil {
+		go flareLoop(rand.New(rand.NewSource(seed + 3)))
+	}
 
-		// Start continuous low-frequency rumble
-		go rumbleLoop()
+	if *demo {
+		startDemo()
 	}
 
 	// Event handling
 	events := make(chan tcell.Event)
 	go func() {
+		defer crashGuard()
 		for {
 			events <- screen.PollEvent()
 		}
 	}()
 
-	ticker := time.NewTicker(time.Millisecond * 50) // 20 FPS
+	// Re-read the config file on SIGHUP
+	reload := make(chan os.Signal, 1)
+	notifyReload(reload)
+
+	// Put the screen and sound away on SIGTSTP before stopping
+	suspendSignal := make(chan os.Signal, 1)
+	notifySuspend(suspendSignal)
+
+	// Palette file changes are applied here too, between frames
+	paletteUpdates := make(chan paletteUpdate)
+	if watchPalette {
+		go watchPaletteFile(paletteFile, paletteUpdates)
+	}
+
+	ticker := time.NewTicker(frameTime)
 	defer ticker.Stop()
+	interval := frameTime
+
+	// With --adaptive, input keeps the frame rate up. Output that isn't
+	// going to a terminal starts out idle.
+	lastInput := time.Now()
+	if !isTerminal(os.Stdout) {
+		lastInput = time.Time{}
+	}
+
+	// A sleep timer ends the session when the fire goes out
+	start := time.Now()
+	if *sleep > 0 {
+		*duration = *sleep
+	}
+
+	// When the logs have all burned away the fire dies out like a sleep
+	// timer ending
+	var burnedOut time.Time
+
+	// A nil channel never fires, so a zero duration runs forever
+	var timeout <-chan time.Time
+	if *duration > 0 {
+		timeout = time.After(*duration)
+	}
 
 	for {
 		select {
+		case <-timeout:
+			return
+		case <-reload:
+			// Handled here rather than in its own goroutine so a reload can
+			// never land in the middle of drawing a frame
+			reloadConfig()
+		case <-suspendSignal:
+			suspend()
+		case u := <-paletteUpdates:
+			applyPaletteUpdate(u)
 		case ev := <-events:
+			lastInput = time.Now()
+			if interval != frameTime {
+				interval = frameTime
+				ticker.Reset(interval)
+			}
+
 			switch ev := ev.(type) {
 			case *tcell.EventResize:
 				screen.Sync()
 				resize()
+				if *dumpPath != "" {
+					dumpState(*dumpPath)
+				}
 			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
+				// The key that ends the demo only takes back control
+				if demoMode {
+					stopDemo()
+					break
+				}
+				if handleKey(ev) {
 					return
 				}
 			}
+		case loudness := <-crackEvents:
+			if paused.Load() {
+				// A still fire doesn't flare
+				break
+			}
+			if flareMode {
+				stoke()
+			}
+			if visualizerMode {
+				surgeOn(loudness)
+			}
+		case req := <-controlRequests:
+			reply, quit := runControl(req.line)
+			req.reply <- reply
+			if quit {
+				return
+			}
 		case <-ticker.C:
-			tick++
-			updateFire()
-
-			screen.SetStyle(tcell.StyleDefault.Background(tcell.ColorDefault).Foreground(tcell.ColorBlack))
-			screen.Clear()
+			if *sleep > 0 {
+				updateSleep(time.Since(start), *sleep)
+			}
 
-			// 1. Draw all sticks first to establish the woodMap on the screen
-			drawEnvironment(1, logCount)
+			// Run as many simulation steps as the time scale has accrued,
+			// catching up on the frames an idle frame rate skips. Nothing
+			// accrues while paused.
+			if !paused.Load() {
+				pendingSteps += timeScale * float64(interval) / float64(frameTime)
+			}
+			for ; pendingSteps >= 1; pendingSteps-- {
+				stepFires()
+			}
 
-			// 2. Draw fire with blending logic
-			drawFireBlended()
+			if consumeMode && burnedOut.IsZero() && allBurnedOut() {
+				burnedOut = time.Now()
+			}
+			if !burnedOut.IsZero() {
+				if time.Since(burnedOut) >= dieOutTime {
+					return
+				}
+				updateSleep(time.Since(burnedOut), dieOutTime)
+			}
+			stepDemo()
+			drawFrame()
+			recordMetrics()
+			if *lightTarget != "" {
+				sendLight()
+			}
 
-			screen.Show()
+			if adaptiveMode && interval == frameTime && time.Since(lastInput) >= idleAfter {
+				interval = idleFrameTime
+				ticker.Reset(interval)
+			}
 		}
 	}
 }
 
-func resize() {
-	width, height = screen.Size()
-
-	// Hearth fills the entire screen
-	hearthLeft = 0
-	hearthRight = width
-
-	// Fire simulation grid
-	fireHeight = height * 2
-	initFire()
-	generateLogs()
-}
-
-func generateLogs() {
-	woodMap = make([]int, width*height)
-	centerX := float64(hearthLeft+hearthRight) / 2.0
-	bottomY := float64(height - 1)
-	aspect := 2.0
-
-	// Sticks should be thin
-	baseRadius := float64(height) / 90.0
-	if baseRadius < 0.4 {
-		baseRadius = 0.4
-	}
-
-	type Log struct {
-		midX, midY     float64
-		dx, dy         float64
-		angle          float64
-		length         float64
-		r              float64
-		depth          float64
-		id             int
-		x1, y1, x2, y2 float64
-	}
-
-	tempLogs := []Log{}
-	numLogs := min(width, 120)
-	// Ensure we have an even number for pairing
-	if numLogs%2 != 0 {
-		numLogs++
-	}
-	sigmaX := float64(width) * 0.25
-
-	// 1. Generate sticks in pairs to ensure balance
-	for i := 0; i < numLogs; i += 2 {
-		// Sample a distance from center
-		offset := math.Abs(rand.NormFloat64() * sigmaX)
-		// Attempt to place a pair (left and right)
-		for side := range []int{0, 1} {
-			var midX, midY float64
-			var length, angle, r float64
-			dir := 1.0
-
-			if side == 0 {
-				dir = -1.0
-			}
-
-			maxAttempts := 15
-
-			for attempt := range make([]struct{}, maxAttempts) {
-				// Each side gets its own variation but same horizontal distance magnitude
-				thisOffset := offset * (0.9 + rand.Float64()*0.2)
-				midX = centerX + (dir * thisOffset)
-				distFromCenter := (midX - centerX) / sigmaX
-
-				maxH := (float64(height) / 3.0) * math.Exp(-distFromCenter*distFromCenter*0.8)
-				length = 7.0 + rand.Float64()*12.0
+// Frame times, normally and once --adaptive has seen no input for idleAfter
+const (
+	frameTime     = 50 * time.Millisecond  // 20 FPS
+	idleFrameTime = 200 * time.Millisecond // 5 FPS
+	idleAfter     = 30 * time.Second
+)
 
-				angle = (rand.Float64() - 0.5) * math.Pi * 0.6
-				r = baseRadius * (0.6 + rand.Float64()*0.8)
-				limitY := bottomY - r - 0.5
-				hRange := maxH
+// Whether to drop to the idle frame rate while there's no input
+var adaptiveMode bool
 
-				if hRange > limitY {
-					hRange = limitY
-				}
-				midY = limitY - rand.Float64()*hRange
-				if len(tempLogs) < 4 {
-					// Seed the first few sticks near the center
-					if math.Abs(midX-centerX) < 5.0 {
-						break
-					}
-					continue
+// updateSleep burns the fire down over the whole --sleep timer and fades the
+// audio out over its last few minutes
+func updateSleep(elapsed, total time.Duration) {
+	remaining := max(total-elapsed, 0)
+	burnLevel = float64(remaining) / float64(total)
 
-				}
+	fade := min(total/3, 5*time.Minute)
+	setAudioLevel(volume * min(float64(remaining)/float64(fade), 1))
+}
 
-				// Proximity check
-				isNear := false
-				proximityLimit := length * 1.5
-				for _, existing := range tempLogs {
-					dx := midX - existing.midX
-					dy := midY - existing.midY
-					if dx*dx+dy*dy < proximityLimit*proximityLimit {
-						isNear = true
-						break
-					}
-				}
+// How long the last flames and sound take to fade once --consume has burned
+// every log away
+const dieOutTime = 10 * time.Second
 
-				if isNear || attempt == maxAttempts-1 {
-					break
-				}
-			}
-			tempLogs = append(tempLogs, Log{
-				midX: midX, midY: midY,
-				angle: angle, length: length, r: r,
-				depth: midY, id: len(tempLogs) + 1,
-			})
+// allBurnedOut reports whether every fire has consumed all of its logs. With
+// no fires on screen there's nothing burning down.
+func allBurnedOut() bool {
+	if len(hearths) == 0 {
+		return false
+	}
+	for _, h := range hearths {
+		if !h.BurnedOut() {
+			return false
 		}
 	}
+	return true
+}
 
-	// 2. Adjust angles: if nothing is underneath the center, make it horizontal
-	for i := range tempLogs {
-		underneath := false
-		for j := range tempLogs {
-			if i == j {
-				continue
-			}
-			// Check if log j is "under" log i (larger Y, similar X)
-			// Using a small horizontal window to define "under"
-			if tempLogs[j].midY > tempLogs[i].midY+0.5 &&
-				math.Abs(tempLogs[j].midX-tempLogs[i].midX) < tempLogs[i].length/3.0 {
-				underneath = true
-				break
-			}
-		}
+// Simulation speed relative to the frame rate, and the bounds for the live
+// keys that adjust it
+const (
+	minTimeScale = 0.1
+	maxTimeScale = 8.0
+)
 
-		if !underneath {
-			tempLogs[i].angle = 0
-			// If it's the bottom stick, make sure it's actually near the bottom
-			// to look like it's resting on the floor.
-			if tempLogs[i].midY > bottomY-5.0 {
-				tempLogs[i].midY = bottomY - tempLogs[i].r - 0.2
-			}
-		}
+var (
+	timeScale    = 1.0
+	pendingSteps float64 // Fractional simulation steps carried to the next frame
+)
 
-		// Recalculate x1, y1, x2, y2 based on final angle
-		dx := math.Cos(tempLogs[i].angle) * tempLogs[i].length / 2.0
-		dy := math.Sin(tempLogs[i].angle) * tempLogs[i].length / 2.0 / aspect
+// changeTimeScale speeds the simulation up (factor > 1) or slows it down
+func changeTimeScale(factor float64) {
+	timeScale = math.Max(minTimeScale, math.Min(maxTimeScale, timeScale*factor))
+	notify(fmt.Sprintf("speed: %.2gx", timeScale))
+}
+
+// changeFlameHeight makes the flames taller (factor > 1) or shorter
+func changeFlameHeight(factor float64) {
+	flameHeight = math.Max(minFlameHeight, math.Min(maxFlameHeight, flameHeight*factor))
+	notify(fmt.Sprintf("flame height: %.2gx", flameHeight))
+}
 
-		// Horizontal clamping
-		mx := tempLogs[i].midX
-		r := tempLogs[i].r
-		if mx-math.Abs(dx)-r < 0 {
-			mx = math.Abs(dx) + r
+// changeWoodLight makes the logs brighter (factor > 1) or dimmer
+func changeWoodLight(factor float64) {
+	woodLight = math.Max(minWoodLight, math.Min(maxWoodLight, woodLight*factor))
+	notify(fmt.Sprintf("wood brightness: %.2g", woodLight))
+}
+
+// stepFires advances every fire by one tick
+func stepFires() {
+	tick++
+	stepStorm()
+	settings := fireSettings()
+	for i, h := range hearths {
+		h.Settings = settings
+		var gone []bool
+		if fireEvents != nil && consumeMode {
+			gone = burnedAway(h.Fire)
 		}
-		if mx+math.Abs(dx)+r > float64(width-1) {
-			mx = float64(width-1) - math.Abs(dx) - r
+		h.Step()
+		for j, l := range h.Logs() {
+			if j < len(gone) && !gone[j] && l.Burned() >= 1 {
+				emitEvent(fireEvent{Type: "log-burned", Hearth: i + 1, Log: l.ID})
+			}
 		}
-
-		tempLogs[i].x1 = mx - dx
-		tempLogs[i].y1 = tempLogs[i].midY - dy
-		tempLogs[i].x2 = mx + dx
-		tempLogs[i].y2 = tempLogs[i].midY + dy
 	}
+}
 
-	// Sort logs by depth
-	sort.Slice(tempLogs, func(i, j int) bool {
-		return tempLogs[i].depth < tempLogs[j].depth
-	})
+// drawFrame renders every fire and shows the result
+func drawFrame() {
+	stepPaletteFade()
+	stepMood()
 
-	logCount = len(tempLogs)
-	for i := range tempLogs {
-		tempLogs[i].id = i + 1
+	if asciiMode {
+		clearScreen(tcell.StyleDefault)
+	} else {
+		clearScreen(tcell.StyleDefault.Background(tcell.ColorDefault).Foreground(tcell.ColorBlack))
 	}
 
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			for i := len(tempLogs) - 1; i >= 0; i-- {
-				l := tempLogs[i]
-				px, py := float64(x), float64(y)*aspect
-				ax, ay := l.x1, l.y1*aspect
-				bx, by := l.x2, l.y2*aspect
-
-				abx, aby := bx-ax, by-ay
-				apx, apy := px-ax, py-ay
-				lenSq := abx*abx + aby*aby
-				if lenSq == 0 {
-					continue
-				}
-				t := (apx*abx + apy*aby) / lenSq
-				if t < 0 {
-					t = 0
-				} else if t > 1 {
-					t = 1
-				}
+	settings := fireSettings()
+	for _, h := range hearths {
+		h.Settings = settings
+		h.draw(screen)
+	}
+	blendResizeFade()
+	flareBoost = 0
+	fadeSurge()
+	if tooSmall {
+		drawTooSmall()
+	}
+	if clockMode && !tooSmall {
+		drawClock()
+	}
 
-				cx, cy := ax+t*abx, ay+t*aby
-				dx, dy := px-cx, py-cy
-				if dx*dx+dy*dy <= (l.r*aspect)*(l.r*aspect) {
-					woodMap[y*width+x] = l.id
-					break
-				}
-			}
-		}
+	// Overlays go on top of everything else
+	if showHelp {
+		drawHelp()
 	}
-}
+	drawStatus()
+	drawNotify()
 
-func initFire() {
-	fire = make([]int, width*fireHeight)
+	screen.Show()
 }
 
-func updateFire() {
-	center := float64(width) / 2.0
-	halfWidth := float64(width) / 2.0
-
-	// Clear the top row of fire to prevent "hanging" artifacts
-	for x := 0; x < width; x++ {
-		fire[x] = 0
+// drawTooSmall centers a note on the screen in place of the fire, cut short
+// if even that doesn't fit
+func drawTooSmall() {
+	const msg = "terminal too small"
+	screenW, screenH := screen.Size()
+	left := max((screenW-len(msg))/2, 0)
+	top := drawTop()
+	for i, r := range msg {
+		screen.SetContent(left+i, top+(screenH-top)/2, r, nil, tcell.StyleDefault)
 	}
+}
 
-	// 1. Propagate and decay
-	for x := 0; x < width; x++ {
-		for y := 1; y < fireHeight; y++ {
-			src := y*width + x
-			pixel := fire[src]
-
-			if pixel == 0 {
-				if src-width >= 0 {
-					fire[src-width] = 0
-				}
-			} else {
-				drift := rand.Intn(3) - 1
-				dstX := x + drift
-				if dstX < 0 {
-					dstX = 0
-				} else if dstX >= width {
-					dstX = width - 1
-				}
+// blankScreen shows one last frame cleared to the terminal's default colors,
+// so no fire colors are left behind in emulators and tmux panes that keep
+// cells after the screen is released
+func blankScreen() {
+	clearScreen(tcell.StyleDefault.Foreground(tcell.ColorDefault).Background(tcell.ColorDefault))
+	// Inline, the shell's prompt comes back where the fire was
+	if inlineRows > 0 {
+		screen.ShowCursor(0, drawTop())
+	}
+	screen.Show()
+}
 
-				dstIndex := (y-1)*width + dstX
-				if dstIndex < 0 {
-					continue
-				}
+// showStill renders a single frame and waits for a keypress
+func showStill() {
+	drawFrame()
 
-				dist := math.Abs(float64(x) - center)
-				normDist := dist / (halfWidth * 0.8) // Reverted to previous width
+	for {
+		switch screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			return
+		}
+	}
+}
 
-				// Slower decay for a larger, taller fire
-				decay := 1 + int(normDist*normDist*6.0)
+// Smallest fire, in cells, that's worth simulating
+const (
+	minFireWidth  = 4
+	minFireHeight = 4
+)
 
-				if y < fireHeight/2 { // Heat carries further up
-					// Occasionally reduce decay to let "licks" of flame go higher
-					if rand.Float64() > 0.8 {
-						decay = 0
-					} else {
-						decay += 1
-					}
-				}
+var tooSmall bool // Whether the region is too small to hold a fire
 
-				newHeat := max(pixel-decay, 0)
-				fire[dstIndex] = newHeat
-			}
-		}
+func resize() {
+	startResizeFade(hearths)
+	screenW, screenH := screen.Size()
+
+	// Clamp the requested region so it stays on screen
+	x := max(min(regionOrigin.x, screenW-1), 0)
+	y := max(min(regionOrigin.y, screenH-1), 0)
+	w, h := screenW-x, screenH-y
+	if regionSize.x > 0 {
+		w = min(w, regionSize.x)
+	}
+	if regionSize.y > 0 {
+		h = min(h, regionSize.y)
+	}
+	// Inline, the fire keeps to the bottom rows whatever the origin's row
+	if inlineRows > 0 {
+		y = drawTop()
+		h = screenH - y
+	}
+	// The margin comes off the bottom, so the logs rest on the row above it
+	// and nothing of the fire reaches into it. A reflection goes between the
+	// two.
+	h -= bottomMargin + reflectionRows
+
+	// A fire any smaller than this is degenerate, so drawFrame explains
+	// instead until the region grows again
+	fireW := w
+	if splitMode {
+		fireW = w / 2
+	}
+	tooSmall = fireW/gridScale < minFireWidth || h/gridScale < minFireHeight
+	logger.Debug("resize", "screen_width", screenW, "screen_height", screenH, "region_width", w, "region_height", h, "too_small", tooSmall)
+	if tooSmall {
+		hearths = nil
+		return
 	}
 
-	// 2. Stable Refuel
-	minLX, maxLX := width, 0
-	for x := 0; x < width; x++ {
-		if getLogHeight(x) > 0 {
-			if x < minLX {
-				minLX = x
-			}
-			if x > maxLX {
-				maxLX = x
-			}
-		}
+	if !splitMode {
+		hearths = []*hearth{newHearth(0, x, y, w, h)}
+		return
 	}
 
-	logSpan := float64(maxLX - minLX)
-	fireSpan := logSpan * 0.8
-	fireCenter := float64(minLX+maxLX) / 2.0
+	// Halve the region, giving an odd column to the left fire
+	leftW := (w + 1) / 2
+	hearths = []*hearth{
+		newHearth(0, x, y, leftW, h),
+		newHearth(1, x+leftW, y, w-leftW, h),
+	}
+}
 
-	for x := 0; x < width; x++ {
-		h := getLogHeight(x)
-		if h <= 0 {
-			continue
-		}
+// hearth is one fire drawn into a region of the screen
+type hearth struct {
+	*fireplace.Fire
+	x, y int // Screen position of the fire's top-left corner
+}
 
-		dist := math.Abs(float64(x) - fireCenter)
-		// Only refuel within the 80% span
-		if dist > fireSpan/2.0 {
-			continue
-		}
+// draw renders the fire onto c at its place on screen, with its reflection
+// under it for --reflection. With --grid-scale each of the fire's cells
+// covers a block of the screen's.
+func (h *hearth) draw(c fireplace.Canvas) {
+	s := fireplace.NewScaled(c, gridScale, h.x, h.y)
+	h.Draw(s, 0, 0)
+	_, fireH := h.Size()
+	h.DrawReflection(s, 0, fireH, reflectionRows/gridScale)
+}
 
-		normDist := dist / (fireSpan / 2.0)
+// Simulation steps run on each new fire before it's first drawn
+var warmupTicks = 60
+
+// newHearth creates fire number i with freshly generated logs in the w by h
+// screen region whose top-left corner is at x, y. With --grid-scale the fire
+// is smaller by that much, centered in the region with its floor at the
+// bottom.
+func newHearth(i, x, y, w, h int) *hearth {
+	fullW, fullH := w, h
+	w, h = w/gridScale, h/gridScale
+	x += (fullW - w*gridScale) / 2
+	y += fullH - h*gridScale
+
+	// Each fire gets its own generator, after the ones the audio uses, so a
+	// given seed always builds the same scene at a given size
+	rng := rand.New(rand.NewSource(seed + 4 + int64(i)))
+	opts := []fireplace.Option{fireplace.WithSettings(fireSettings()), fireplace.WithRand(rng)}
+	if logs := sceneLogs(i, w, h); logs != nil {
+		opts = append(opts, fireplace.WithLogs(logs))
+	}
+	f := fireplace.NewFire(w, h, opts...)
 
-		if rand.Float64() > normDist*0.9 {
-			// Inject heat at various depths within logs
-			for range []int{0, 1, 2} { // More heat sources
-				// Fire extends higher into the bundle
-				d := rand.Intn(h*3/4 + 1)
-				fireY := (height - 1 - d) * 2
-				if fireY >= 0 && fireY < fireHeight {
-					fire[fireY*width+x] = 36
-				}
-			}
-		}
+	// Start from a fire that's already burning rather than one climbing
+	// out of a cold grid
+	for range warmupTicks {
+		f.Step()
 	}
+	return &hearth{Fire: f, x: x, y: y}
 }
 
-func drawFireBlended() {
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			sy1 := y * 2
-			sy2 := y*2 + 1
-
-			if sy2*width+x >= len(fire) {
-				continue
-			}
+// fireSettings gathers the flags and runtime state the fires follow
+func fireSettings() fireplace.Settings {
+	breathe := 0.0
+	if breatheMode {
+		breathe = breatheDepth
+	}
 
-			heat1 := fire[sy1*width+x]
-			heat2 := fire[sy2*width+x]
+	return fireplace.Settings{
+		Palette: surgePalette(firePalette()), Layout: logLayout, HearthWidth: hearthWidth / gridScale,
+		Logs: logsWanted, NoLogs: noLogsMode, NoFlatten: noFlatten, Mask: maskImage,
+		LogSpacing: logSpacing, CoreLogs: coreLogs, CoreSpread: coreSpread, Down: direction == "down",
+		FireSpan: fireSpanRatio, FuelProfile: fuelProfile, LickChance: lickChance,
+		Turbulence: turbulence, Convection: convection, EdgeFalloff: edgeFalloff, FlameHeight: flameHeight, Wrap: wrapMode, Chimney: chimneyMode, FlueX: flueX, Wind: wind, NoTopClear: noTopClear, Haze: hazeMode,
+		HeatSources: heatSources, EmberRate: emberRate, EmberSpeed: emberSpeed, EmberLife: emberLife, RefuelDepth: refuelDepth, MaxHeat: maxHeat, BurnLevel: burnLevel * micIntensity() * intensity,
+		ASCII: asciiMode, Ambient: ambientMode, Smooth: smoothMode,
+		Dither: ditherMode, Floor: emberFloor, FloorLine: floorLine, Flare: flareBoost, BlendCap: blendCap,
+		Consume: consumeMode, GlyphRamp: glyphRamp, Subcell: subcellMode, Block: blockChar, Coals: coalsMode, WoodColor: woodColor, WoodLight: woodLight, LogGlow: logGlow, Vignette: vignette,
+		Texture: texture, Breathe: breathe,
+	}
+}
 
-			// Only process if there is actual heat to display
-			if heat1 < 4 && heat2 < 4 {
-				continue
-			}
+// useCampfire switches to a compact teepee of sticks with a narrow, tall
+// flame that keeps its size on large terminals
+func useCampfire() {
+	logLayout = "teepee"
+	hearthWidth = 40
+	fireSpanRatio = 0.6
+	lickChance = 0.35
+}
 
-			// Get existing color from the sticks
-			_, existingStyle, _ := screen.Get(x, y)
-			existingFg, existingBg, _ := existingStyle.Decompose()
+// Audio functions for fireplace crackling sounds
 
-			// FIX: Ensure we don't blend with the terminal's default white/grey
-			// If it's the default foreground/background, treat it as black
-			if existingFg == tcell.ColorWhite || existingFg == tcell.ColorDefault {
-				existingFg = tcell.ColorBlack
-			}
-			if existingBg == tcell.ColorWhite || existingBg == tcell.ColorDefault {
-				existingBg = tcell.ColorBlack
-			}
+// Audio sample rates --sample-rate accepts. The sound was tuned at
+// baseSampleRate, and per-sample coefficients are scaled from it.
+var sampleRates = []int{22050, 32000, 44100, 48000, 88200, 96000}
 
-			// Map heat to fire colors
-			fireC1 := colors[clamp(heat1)]
-			fireC2 := colors[clamp(heat2)]
+const baseSampleRate = 44100
 
-			// Blend fire colors with existing stick/background colors
-			c1 := blendColors(existingFg, fireC1, heat1)
-			c2 := blendColors(existingBg, fireC2, heat2)
+var (
+	sampleRate = baseSampleRate // Sample rate asked for with --sample-rate
+	audioRate  = baseSampleRate // Rate the audio context was opened at, which every sound uses
+)
 
-			style := tcell.StyleDefault.Foreground(c1).Background(c2)
-			screen.SetContent(x, y, '▀', nil, style)
-		}
-	}
+// rateRatio is the number of base-rate samples each sample stands in for
+func rateRatio() float64 {
+	return float64(baseSampleRate) / float64(audioRate)
 }
 
-func blendColors(base, overlay tcell.Color, heat int) tcell.Color {
-	// If no heat, return the base (wood or black)
-	if heat <= 0 {
-		return base
-	}
+// maxClipBytes is the longest clip playWoodCrack can produce, 0.2s of 16-bit
+// stereo
+func maxClipBytes() int {
+	return audioRate * 4 / 5
+}
 
-	br, bg, bb := base.RGB()
-	or, og, ob := overlay.RGB()
+// samplePool recycles clip buffers so frequent crackles don't churn the GC
+var samplePool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, maxClipBytes())
+		return &buf
+	},
+}
 
-	// Use heat as the blend factor
-	alpha := float64(heat) / 40.0
+// getSampleBuffer returns a pooled buffer resized to n bytes
+func getSampleBuffer(n int) *[]byte {
+	buf := samplePool.Get().(*[]byte)
+	if cap(*buf) < n {
+		*buf = make([]byte, n)
+	}
+	*buf = (*buf)[:n]
+	return buf
+}
 
-	// Ensure high heat doesn't blow out to white by capping the intensity
-	if alpha > 0.85 {
-		alpha = 0.85
+// playSamples mixes a pooled clip into the output, which returns the buffer
+// to the pool once it has played
+func playSamples(buf *[]byte) {
+	if audioMixer == nil {
+		samplePool.Put(buf)
+		return
 	}
+	audioMixer.add(buf)
+}
 
-	r := int32(float64(br)*(1.0-alpha) + float64(or)*alpha)
-	g := int32(float64(bg)*(1.0-alpha) + float64(og)*alpha)
-	b := int32(float64(bb)*(1.0-alpha) + float64(ob)*alpha)
+var (
+	audioOnce sync.Once // Guards initAudio, since oto allows only one context per process
+	audioErr  error     // Why the audio context couldn't be opened (nil if it was, or wasn't tried)
+)
 
-	return tcell.NewRGBColor(clampColor(r), clampColor(g), clampColor(b))
+// newAudioContext opens oto's context, and can be swapped out where there's
+// no sound device to open
+var newAudioContext = oto.NewContext
+
+// initAudio opens the audio context on the first call and returns it, or nil
+// if audio isn't available, leaving the reason in audioErr. Later calls
+// return the same context rather than asking oto for another.
+func initAudio() *oto.Context {
+	audioOnce.Do(func() {
+		// A reloaded config can't change the rate of an open context, so
+		// the sounds follow the rate it was opened at
+		audioRate = sampleRate
+		ctx, readyChan, err := newAudioContext(audioRate, 2, 2)
+		if err != nil {
+			// Audio is optional, continue without it
+			audioErr = err
+			logger.Warn("audio unavailable", "err", err)
+			return
+		}
+		<-readyChan
+		audioCtx = ctx
+	})
+	return audioCtx
 }
 
-func drawEnvironment(minID, maxID int) {
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			logID := 0
-			if x >= 0 && x < width && y >= 0 && y < height {
-				logID = woodMap[y*width+x]
-			}
-
-			if logID >= minID && logID <= maxID {
-				depth := float64(logID) / float64(logCount)
+// Average crackle events a second
+const (
+	crackRate       = 0.22  // Big cracks of the wood
+	sparseCrackRate = 0.045 // Big cracks in sparse mode, one in five
+	sizzleRate      = 2.2   // Short, high sizzles
+)
 
-				// Base stick colors (dark browns)
-				br := int32(25 + depth*35)
-				bg := int32(15 + depth*20)
-				bb := int32(10 + depth*10)
+// How irregular the gaps between crackles are, from 0 (evenly spaced) to 1
+// (as random as raindrops)
+var crackleJitter = 1.0
+
+// audioLoop plays crackles and sizzles at random. Sparse mode, for coals,
+// keeps only one in five of the big cracks. Spread is passed on to each
+// crack.
+//
+// Each event is scheduled a random wait after the last. At full jitter the
+// waits are exponentially distributed, so the events arrive independently at
+// their average rates; less jitter evens them out towards the mean gap.
+func audioLoop(rng *rand.Rand, sparse bool, spread float64) {
+	if audioCtx == nil {
+		return
+	}
+	defer stopOnPanic("crackling")
 
-				// Get local fire heat for glow
-				heat1 := 0
-				heat2 := 0
-				if y*2 < fireHeight {
-					heat1 = fire[(y*2)*width+x]
-				}
-				if y*2+1 < fireHeight {
-					heat2 = fire[(y*2+1)*width+x]
-				}
-				avgHeat := (heat1 + heat2) / 2
+	rate := crackRate
+	if sparse {
+		rate = sparseCrackRate
+	}
 
-				// Add fire glow to the stick
-				r := br + int32(avgHeat*5)
-				g := bg + int32(avgHeat*2)
-				b := bb
+	for {
+		cracks := rate * crackleScale()
+		total := cracks + sizzleRate
+		wait := (1 - crackleJitter + crackleJitter*rng.ExpFloat64()) / total
+		time.Sleep(time.Duration(wait * float64(time.Second)))
+		if audioPaused() {
+			continue
+		}
+		level := audioLevel()
 
-				baseColor := tcell.NewRGBColor(clampColor(r), clampColor(g), clampColor(b))
-				darkColor := tcell.NewRGBColor(clampColor(r/2), clampColor(g/2), clampColor(b/2))
+		if rng.Float64()*total < cracks {
+			// Wood cracking: Sharp mid-frequency crack with decay
+			loudness := 0.3 + rng.Float64()/10.0
+			playWoodCrack(rng, 0.08+rng.Float64()*0.12, loudness*level, spread)
+			signalCrack(loudness / 0.4)
+			emitEvent(fireEvent{Type: "crack", Loudness: loudness / 0.4, Spread: spread})
+		} else {
+			// The "Sizzle": High frequency, very short "spark"
+			gain := float64(rng.Intn(50)-30) / 100.0 * level
+			playWhiteNoise(rng, 0.01, 6000, 8000, gain)
+			emitEvent(fireEvent{Type: "sizzle"})
+		}
+	}
+}
 
-				noise := (x*13 + y*37 + logID*7) % 10
-				var style tcell.Style
+// Master audio volume from 0 to 1, shared with the audio goroutines. An unset
+// value means full volume.
+var masterLevel atomic.Value
 
-				// Texture characters
-				chars := []rune{' ', ' ', '.', ',', '\'', '`', '.', ' ', ' ', ' '}
-				char := chars[noise%len(chars)]
+// Volume set with --volume, from 0 to 1, which --sleep fades out from
+var volume = 1.0
 
-				if noise > 5 {
-					style = tcell.StyleDefault.Background(darkColor).Foreground(baseColor)
-				} else {
-					style = tcell.StyleDefault.Background(baseColor).Foreground(darkColor)
-				}
+// Whether the sound has been muted, which silences it at any volume
+var muted atomic.Bool
 
-				screen.SetContent(x, y, char, nil, style)
-			}
-		}
+func toggleMute() {
+	muted.Store(!muted.Load())
+	if muted.Load() {
+		notify("muted")
+	} else {
+		notify("unmuted")
 	}
 }
 
-func clampColor(v int32) int32 {
-	if v < 0 {
-		return 0
-	}
-	if v > 255 {
-		return 255
-	}
-	return v
+func setAudioLevel(v float64) {
+	masterLevel.Store(v)
 }
 
-func clampFloat32(v, min, max int32) int32 {
-	if v < min {
-		return min
-	}
-	if v > max {
-		return max
-	}
-	return v
+// Scale of how often the wood cracks, shared with the crackling goroutine. An
+// unset value means the usual rate.
+var crackleRate atomic.Value
+
+// How often the wood cracks as set with --crackle-rate, which moods ease the
+// scale away from
+var baseCrackles = 1.0
+
+const maxCrackles = 4.0
+
+func setCrackleScale(v float64) {
+	crackleRate.Store(v)
 }
 
-func clamp(h int) int {
-	if h < 0 {
-		return 0
-	}
-	if h > 36 {
-		return 36
+func crackleScale() float64 {
+	if v, ok := crackleRate.Load().(float64); ok {
+		return v
 	}
-	return h
+	return 1
 }
 
-// Returns the height of the wood from the bottom at column x
-func getLogHeight(x int) int {
-	if x < 0 || x >= width {
+func audioLevel() float64 {
+	if muted.Load() || audioPaused() {
 		return 0
 	}
-	// Scan from top (0) to bottom (height-1)
-	for y := 0; y < height; y++ {
-		if woodMap[y*width+x] != 0 {
-			// Found top of wood
-			return height - 1 - y
-		}
-	}
-	return 0
-}
-
-func isWood(x, y int) bool {
-	if x < 0 || x >= width || y < 0 || y >= height {
-		return false
+	if v, ok := masterLevel.Load().(float64); ok {
+		return v
 	}
-	return woodMap[y*width+x] != 0
+	return 1
 }
 
-// Audio functions for fireplace crackling sounds
+// crackEvents signals a loud wood crack to the renderer, with how loud it was
+// from 0 to 1
+var crackEvents = make(chan float64, 1)
 
-func initAudio() {
-	var readyChan chan struct{}
-	var err error
-	audioCtx, readyChan, err = oto.NewContext(44100, 2, 2)
-	if err != nil {
-		// Audio is optional, continue without it
-		audioCtx = nil
-		return
+// signalCrack tells the renderer a loud crack happened, dropping the event if
+// one is already pending
+func signalCrack(loudness float64) {
+	select {
+	case crackEvents <- loudness:
+	default:
 	}
-	<-readyChan
 }
 
-func audioLoop() {
-	if audioCtx == nil {
-		return
-	}
-
+// flareLoop stands in for audioLoop's cracks when there is no audio,
+// raising flares at roughly the same average rate
+func flareLoop(rng *rand.Rand) {
+	defer stopOnPanic("flares")
 	for {
-		R := rand.Intn(100000)
-
-		if R > 99000 {
-			// Wood cracking: Sharp mid-frequency crack with decay
-			gain := 0.3 + rand.Float64()/10.0
-			playWoodCrack(0.08+rand.Float64()*0.12, gain)
-		} else if R < 10000 {
-			// The "Sizzle": High frequency, very short "spark"
-			gain := float64((R/200)-30) / 100.0
-			playWhiteNoise(0.01, 6000, 8000, gain)
-		} else {
-			time.Sleep(50 * time.Millisecond)
-		}
+		time.Sleep(time.Duration(rng.ExpFloat64() * float64(4500*time.Millisecond)))
+		loudness := 0.75 + rng.Float64()/4
+		signalCrack(loudness)
+		emitEvent(fireEvent{Type: "flare", Loudness: loudness})
 	}
 }
 
-func playWhiteNoise(duration float64, _ int, _ int, gain float64) {
+func playWhiteNoise(rng *rand.Rand, duration float64, lowFreq int, highFreq int, gain float64) {
 	if audioCtx == nil {
 		return
 	}
 
-	sampleRate := 44100
-	numSamples := int(float64(sampleRate) * duration)
-	samples := make([]byte, numSamples*4) // 16-bit stereo samples
+	numSamples := int(float64(audioRate) * duration)
+	buf := getSampleBuffer(numSamples * 4) // 16-bit stereo samples
+	samples := *buf
 
 	// Apply fade in/out for the sizzle effect
-	fadeLen := int(0.02 * float64(sampleRate))
+	fadeLen := int(0.02 * float64(audioRate))
+
+	// One-pole high-pass at lowFreq followed by a one-pole low-pass at
+	// highFreq, giving a band of noise between the two
+	dt := 1.0 / float64(audioRate)
+	hpRC := 1.0 / (2.0 * math.Pi * float64(lowFreq))
+	lpRC := 1.0 / (2.0 * math.Pi * float64(highFreq))
+	hpAlpha := hpRC / (hpRC + dt)
+	lpAlpha := dt / (lpRC + dt)
+	var prevWhite, highPassed, filtered float64
 
 	for i := range numSamples {
 		// Generate white noise
-		white := rand.Float64()*2.0 - 1.0
+		white := rng.Float64()*2.0 - 1.0
 
-		// Simple highpass filter approximation (just attenuate by position)
-		filtered := white * 0.3
+		// Band-pass the noise for a crisp spark
+		highPassed = hpAlpha * (highPassed + white - prevWhite)
+		prevWhite = white
+		filtered += lpAlpha * (highPassed - filtered)
 
 		// Apply fade envelope
 		envelope := 1.0
@@ -671,45 +1390,75 @@ func playWhiteNoise(duration float64, _ int, _ int, gain float64) {
 		}
 
 		// Apply gain and envelope
-		sample := filtered * gain * envelope * 32767.0
-		if sample > 32767 {
-			sample = 32767
-		}
-		if sample < -32768 {
-			sample = -32768
-		}
-
-		s := int16(sample)
-		base := i * 4
-		samples[base] = byte(s)
-		samples[base+1] = byte(s >> 8)
-		samples[base+2] = byte(s)
-		samples[base+3] = byte(s >> 8)
+		s := toSample(filtered * gain * envelope)
+		writeStereoSample(samples, i*4, s, s)
 	}
 
-	player := audioCtx.NewPlayer(bytes.NewReader(samples))
-	player.Play()
+	playSamples(buf)
 }
 
-func playWoodCrack(duration float64, gain float64) {
+// How far apart a crack's two channels sound, from 0 (the same noise in
+// both, a point source) to 1 (independent noise on each side)
+var crackSpread float64
+
+// A crack's timbre: the scale of its filters' cutoffs (1 = standard, higher
+// is brighter) and how fast it dies away across its length (12 = standard,
+// higher is snappier)
+var (
+	crackTone  = 1.0
+	crackDecay = 12.0
+)
+
+// Limits for --crack-tone and --crack-decay
+const (
+	minCrackTone, maxCrackTone   = 0.25, 4.0
+	minCrackDecay, maxCrackDecay = 2.0, 40.0
+)
+
+// playWoodCrack plays a sharp crack of filtered noise. With a spread above
+// 0 the right channel's noise is mixed with a second stream, run through
+// filters of its own, so the crack sounds wide instead of coming from a
+// single point.
+func playWoodCrack(rng *rand.Rand, duration float64, gain float64, spread float64) {
 	if audioCtx == nil {
 		return
 	}
 
-	sampleRate := 44100
-	numSamples := int(float64(sampleRate) * duration)
-	samples := make([]byte, numSamples*4) // 16-bit stereo samples
+	buf := getSampleBuffer(int(float64(audioRate)*duration) * 4) // 16-bit stereo samples
+	synthCrack(*buf, rng, gain, spread)
+	playSamples(buf)
+}
 
-	// State for filtered noise
+// synthCrack fills samples, as 16-bit stereo, with a crack lasting all of
+// them. It draws only from rng, so a given seed always makes the same
+// waveform.
+func synthCrack(samples []byte, rng *rand.Rand, gain float64, spread float64) {
+	numSamples := len(samples) / 4
+
+	// State for filtered noise, with the filters' memory kept the same
+	// length in time at any sample rate. Raising a filter's coefficient to
+	// a power scales its cutoff frequency by it, which is how the tone
+	// brightens or deepens the crack.
 	var filterState1, filterState2 float64
+	var sideState1, sideState2 float64
+	power := rateRatio() * crackTone
+	keep1, keep2 := math.Pow(0.85, power), math.Pow(0.75, power)
 
 	for i := range numSamples {
 		// Generate aggressive noise burst
-		noise := rand.Float64()*2.0 - 1.0
+		noise := rng.Float64()*2.0 - 1.0
 
 		// Apply aggressive bandpass filtering to create "snapping" texture
-		filterState1 = filterState1*0.85 + noise*0.15
-		filterState2 = filterState2*0.75 + (filterState1-filterState2)*0.25
+		filterState1 = filterState1*keep1 + noise*(1-keep1)
+		filterState2 = filterState2*keep2 + (filterState1-filterState2)*(1-keep2)
+
+		// Equal-power mixing, so widening the crack doesn't quiet it
+		sideNoise := noise
+		if spread > 0 {
+			sideNoise = noise*math.Sqrt(1-spread) + (rng.Float64()*2.0-1.0)*math.Sqrt(spread)
+		}
+		sideState1 = sideState1*keep1 + sideNoise*(1-keep1)
+		sideState2 = sideState2*keep2 + (sideState1-sideState2)*(1-keep2)
 
 		// Sharp impulse at the start for the initial crack
 		progress := float64(i) / float64(numSamples)
@@ -721,9 +1470,10 @@ func playWoodCrack(duration float64, gain float64) {
 
 		// Main crack sound is mostly noise with filtering
 		crack := filterState2*0.9 + noise*0.1 + impulse
+		sideCrack := sideState2*0.9 + sideNoise*0.1 + impulse
 
 		// Very fast exponential decay
-		envelope := math.Exp(-progress * 12.0)
+		envelope := math.Exp(-progress * crackDecay)
 
 		// Extremely sharp attack (almost instant)
 		if progress < 0.003 {
@@ -731,70 +1481,127 @@ func playWoodCrack(duration float64, gain float64) {
 		}
 
 		// Apply gain and envelope
-		sample := crack * gain * envelope * 32767.0
-		if sample > 32767 {
-			sample = 32767
-		}
-		if sample < -32768 {
-			sample = -32768
-		}
-
-		s := int16(sample)
-		base := i * 4
-		samples[base] = byte(s)
-		samples[base+1] = byte(s >> 8)
-		samples[base+2] = byte(s)
-		samples[base+3] = byte(s >> 8)
+		left := toSample(crack * gain * envelope)
+		right := toSample(sideCrack * gain * envelope)
+		writeStereoSample(samples, i*4, left, right)
 	}
-
-	player := audioCtx.NewPlayer(bytes.NewReader(samples))
-	player.Play()
 }
 
+// How far the right channel's brown noise strays from the left's, from 0
+// (mono) to 1 (independent)
+var rumbleWidth float64
+
+// How finely the rumble is worked out: "high" computes every sample, "low"
+// only every lowRumbleEvery-th and joins them with straight lines
+var rumbleQuality = "high"
+
+// How much louder the rumble gets in the strongest --storm gust
+const gustRumble = 2.0
+
+// Samples a computed rumble sample covers at --rumble-quality low
+const lowRumbleEvery = 4
+
 // RumbleReader generates continuous low-frequency rumble audio
 type RumbleReader struct {
+	rng          *rand.Rand
 	sampleOffset int
+	state        float64 // The brown noise's walk
+	failed       bool    // Set after a panic, from then on the rumble is silent
+
+	// The right channel mixes in a brown noise of its own, from its own
+	// generator so the left channel plays the same whatever the width
+	width     float64
+	sideRng   *rand.Rand
+	sideState float64
+
+	// Samples each computed one covers, and the last computed pair, which
+	// the next are drawn from
+	every               int
+	lastLeft, lastRight float64
 }
 
 func (r *RumbleReader) Read(p []byte) (n int, err error) {
+	// Read runs on oto's goroutine, so a panic here can't be left to the
+	// other guards. Recover it and carry on with silence.
+	if r.failed {
+		clear(p)
+		return len(p), nil
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			r.failed = true
+			recordFailure("rumble", v)
+			clear(p)
+			n, err = len(p), nil
+		}
+	}()
+
+	r.synth(p, audioLevel()*(1+gustRumble*gustStrength()))
+	return len(p), nil
+}
+
+// synth fills p, as 16-bit stereo, with the next stretch of rumble at
+// level. It draws only from the reader's generators, so a reader seeded
+// the same always makes the same waveform.
+func (r *RumbleReader) synth(p []byte, level float64) {
 	numSamples := len(p) / 4
+	rng := r.rng
+
+	// The random walks were tuned per sample at the base rate. Their steps
+	// scale with the square root of the time a sample covers, their decays
+	// with its power, and the chances of rare events with the time itself.
+	// At low quality a computed sample covers several output ones.
+	every := max(r.every, 1)
+	ratio := rateRatio() * float64(every)
+	step := math.Sqrt(ratio)
 
 	// State for multiple overlapping chaotic oscillators
 	var chaos1, chaos2, chaos3 float64
 
-	for i := range numSamples {
+	for j := range (numSamples + every - 1) / every {
 		// Vary brown noise generation parameters randomly (gentler)
-		whiteAmp := 0.008 + rand.Float64()*0.006
-		white := (rand.Float64()*2.0 - 1.0) * whiteAmp
+		whiteAmp := (0.008 + rng.Float64()*0.006) * step
+		white := (rng.Float64()*2.0 - 1.0) * whiteAmp
 
 		// Vary decay coefficient subtly for timbral variation
-		decay := 0.994 + rand.Float64()*0.003
-		rumbleState = (rumbleState + white) * decay
+		decay := math.Pow(0.994+rng.Float64()*0.003, ratio)
+		r.state = (r.state + white) * decay
 
 		// Keep brown noise bounded
-		if rumbleState > 1.0 {
-			rumbleState = 1.0
-		} else if rumbleState < -1.0 {
-			rumbleState = -1.0
+		if r.state > 1.0 {
+			r.state = 1.0
+		} else if r.state < -1.0 {
+			r.state = -1.0
+		}
+
+		// The right channel's brown noise walks the same way on its own
+		side := r.state
+		if r.width > 0 {
+			sideWhite := (r.sideRng.Float64()*2.0 - 1.0) * whiteAmp
+			r.sideState = min(max((r.sideState+sideWhite)*decay, -1.0), 1.0)
+
+			// Mixing the power rather than the amplitude keeps the right
+			// channel as loud as the left at any width
+			side = r.state*math.Sqrt(1-r.width) + r.sideState*math.Sqrt(r.width)
 		}
 
 		// Rare, gentle impulses - subtle deep movements
 		impulse := 0.0
-		if rand.Float64() < 0.0001 {
-			impulse = (rand.Float64()*2.0 - 1.0) * (0.1 + rand.Float64()*0.15)
+		if rng.Float64() < 0.0001*ratio {
+			impulse = (rng.Float64()*2.0 - 1.0) * (0.1 + rng.Float64()*0.15)
 		}
 
 		// Multiple chaotic low-frequency oscillators with gentler random walks
-		chaos1 += (rand.Float64()*2.0 - 1.0) * 0.003
-		chaos1 *= 0.998 + rand.Float64()*0.002
+		chaos1 += (rng.Float64()*2.0 - 1.0) * 0.003 * step
+		chaos1 *= math.Pow(0.998+rng.Float64()*0.002, ratio)
 		if chaos1 > 0.3 {
 			chaos1 = 0.3
 		} else if chaos1 < -0.3 {
 			chaos1 = -0.3
 		}
 
-		chaos2 += (rand.Float64()*2.0 - 1.0) * 0.005
-		chaos2 *= 0.997 + rand.Float64()*0.003
+		chaos2 += (rng.Float64()*2.0 - 1.0) * 0.005 * step
+		chaos2 *= math.Pow(0.997+rng.Float64()*0.003, ratio)
 		if chaos2 > 0.35 {
 			chaos2 = 0.35
 		} else if chaos2 < -0.35 {
@@ -802,8 +1609,8 @@ func (r *RumbleReader) Read(p []byte) (n int, err error) {
 		}
 
 		// Very slow chaos for subtle deep undertones
-		if rand.Float64() < 0.03 {
-			chaos3 += (rand.Float64()*2.0 - 1.0) * 0.08
+		if rng.Float64() < 0.03*ratio {
+			chaos3 += (rng.Float64()*2.0 - 1.0) * 0.08
 			chaos3 *= 0.995
 			if chaos3 > 0.25 {
 				chaos3 = 0.25
@@ -813,53 +1620,56 @@ func (r *RumbleReader) Read(p []byte) (n int, err error) {
 		}
 
 		// Low-pass filter with subtle random coefficient
-		filterAmt := 0.75 + rand.Float64()*0.15
-		rumble := rumbleState * filterAmt
+		filterAmt := 0.75 + rng.Float64()*0.15
+		rumble := r.state * filterAmt
+		sideRumble := side * filterAmt
 
 		// Combine chaotic elements with reduced mixing
-		rumble += chaos1*0.15 + chaos2*0.12 + chaos3*0.1 + impulse
+		shared := chaos1*0.15 + chaos2*0.12 + chaos3*0.1 + impulse
 
 		// Very rarely inject subtle burst of noise
-		if rand.Float64() < 0.0005 {
-			rumble += (rand.Float64()*2.0 - 1.0) * 0.08
+		if rng.Float64() < 0.0005*ratio {
+			shared += (rng.Float64()*2.0 - 1.0) * 0.08
 		}
+		rumble += shared
+		sideRumble += shared
 
 		// Much quieter base gain for subtle background
-		gain := 0.06 + (rand.Float64() * 0.05)
-
-		sample := rumble * gain * 32767.0
+		gain := (0.06 + (rng.Float64() * 0.05)) * level
+
+		// Draw a line to the new sample from the last, across the output
+		// samples it covers
+		nextLeft, nextRight := rumble*gain, sideRumble*gain
+		for t := 1; t <= every; t++ {
+			i := j*every + t - 1
+			if i >= numSamples {
+				break
+			}
+			lv, rv := nextLeft, nextRight
+			if t < every {
+				f := float64(t) / float64(every)
+				lv = r.lastLeft + (nextLeft-r.lastLeft)*f
+				rv = r.lastRight + (nextRight-r.lastRight)*f
+			}
 
-		if sample > 32767 {
-			sample = 32767
+			writeStereoSample(p, i*4, toSample(lv), toSample(rv))
 		}
-		if sample < -32768 {
-			sample = -32768
-		}
-
-		s := int16(sample)
-		base := i * 4
-		p[base] = byte(s)
-		p[base+1] = byte(s >> 8)
-		p[base+2] = byte(s)
-		p[base+3] = byte(s >> 8)
+		r.lastLeft, r.lastRight = nextLeft, nextRight
 	}
 
 	r.sampleOffset += numSamples
-	return len(p), nil
 }
 
-func rumbleLoop() {
-	if audioCtx == nil {
-		return
-	}
-
-	// Create a continuous streaming player
-	rumbleReader := &RumbleReader{}
-	player := audioCtx.NewPlayer(rumbleReader)
-	defer player.Close()
-
-	player.Play()
+// toSample converts v, nominally between -1 and 1, to a 16-bit sample,
+// clipping anything louder
+func toSample(v float64) int16 {
+	return int16(min(max(v*32767.0, -32768), 32767))
+}
 
-	// Keep the goroutine alive
-	select {}
+// writeStereoSample writes one frame of the output format at offset in buf:
+// the left then the right channel, each a signed 16-bit little-endian
+// sample. Negative samples go out as their two's complement bits.
+func writeStereoSample(buf []byte, offset int, left, right int16) {
+	binary.LittleEndian.PutUint16(buf[offset:], uint16(left))
+	binary.LittleEndian.PutUint16(buf[offset+2:], uint16(right))
 }
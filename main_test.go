@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hajimehoshi/oto/v2"
+)
+
+// waveHash returns an FNV-1a hash of a synthesized waveform
+func waveHash(wave []byte) string {
+	h := fnv.New64a()
+	h.Write(wave)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// The golden waveforms are made at the base sample rate with the default
+// tone and decay
+func useDefaultSound(t *testing.T) {
+	oldRate, oldTone, oldDecay := audioRate, crackTone, crackDecay
+	audioRate, crackTone, crackDecay = baseSampleRate, 1, 12
+	t.Cleanup(func() { audioRate, crackTone, crackDecay = oldRate, oldTone, oldDecay })
+}
+
+// TestCrackGolden makes a crack from a fixed seed and compares it against
+// the known waveform. A different hash means the synthesis changed: if that
+// was meant, listen to it and update the golden hash.
+func TestCrackGolden(t *testing.T) {
+	useDefaultSound(t)
+	const want = "844ae8aacd4264d9"
+
+	crack := make([]byte, baseSampleRate/5*4) // 0.2s of 16-bit stereo
+	synthCrack(crack, rand.New(rand.NewSource(1)), 0.35, 0.5)
+	if got := waveHash(crack); got != want {
+		t.Errorf("crack waveform hash %s, want %s", got, want)
+	}
+}
+
+// TestRumbleGolden makes two seconds of rumble at both qualities from fixed
+// seeds, in the chunks oto reads it in, and compares them against the known
+// waveforms
+func TestRumbleGolden(t *testing.T) {
+	useDefaultSound(t)
+	const chunk = 4096
+
+	tests := []struct {
+		name  string
+		every int
+		want  string
+	}{
+		{"high quality", 1, "5edac7b07c118d6d"},
+		{"low quality", lowRumbleEvery, "d35b1467b96ee364"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &RumbleReader{rng: rand.New(rand.NewSource(1)), width: 0.5, sideRng: rand.New(rand.NewSource(2)), every: tt.every}
+			wave := make([]byte, baseSampleRate*4*2)
+			for p := wave; len(p) > 0; p = p[min(chunk, len(p)):] {
+				r.synth(p[:min(chunk, len(p))], 1)
+			}
+			if got := waveHash(wave); got != tt.want {
+				t.Errorf("rumble waveform hash %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWriteStereoSample checks a frame goes out as the left then the right
+// sample, low byte first, with negative samples as their two's complement
+// bits, and nothing around it is touched
+func TestWriteStereoSample(t *testing.T) {
+	tests := []struct {
+		left, right int16
+		want        [4]byte
+	}{
+		{0, 0, [4]byte{0x00, 0x00, 0x00, 0x00}},
+		{1, -1, [4]byte{0x01, 0x00, 0xFF, 0xFF}},
+		{-1, 1, [4]byte{0xFF, 0xFF, 0x01, 0x00}},
+		{0x1234, -0x1234, [4]byte{0x34, 0x12, 0xCC, 0xED}},
+		{32767, -32768, [4]byte{0xFF, 0x7F, 0x00, 0x80}},
+		{-32768, 32767, [4]byte{0x00, 0x80, 0xFF, 0x7F}},
+		{-256, 255, [4]byte{0x00, 0xFF, 0xFF, 0x00}},
+	}
+	for _, tt := range tests {
+		buf := []byte{0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA}
+		writeStereoSample(buf, 2, tt.left, tt.right)
+		want := []byte{0xAA, 0xAA, tt.want[0], tt.want[1], tt.want[2], tt.want[3], 0xAA, 0xAA}
+		if !bytes.Equal(buf, want) {
+			t.Errorf("writeStereoSample(%d, %d) wrote % X, want % X", tt.left, tt.right, buf, want)
+		}
+	}
+}
+
+// TestInitAudioOnce calls initAudio twice, with oto stubbed out, and checks
+// the second call hands back what the first opened without asking for
+// another context
+func TestInitAudioOnce(t *testing.T) {
+	savedNew, savedCtx, savedErr := newAudioContext, audioCtx, audioErr
+	t.Cleanup(func() {
+		newAudioContext, audioCtx, audioErr = savedNew, savedCtx, savedErr
+		audioOnce = sync.Once{}
+	})
+
+	tests := []struct {
+		name string
+		ctx  *oto.Context
+		err  error
+	}{
+		{"opened", new(oto.Context), nil},
+		{"no device", nil, errors.New("no sound device")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audioOnce, audioCtx, audioErr = sync.Once{}, nil, nil
+			opened := 0
+			newAudioContext = func(rate, channels, format int) (*oto.Context, chan struct{}, error) {
+				opened++
+				ready := make(chan struct{})
+				close(ready)
+				return tt.ctx, ready, tt.err
+			}
+
+			first := initAudio()
+			second := initAudio()
+			if opened != 1 {
+				t.Errorf("a context was opened %d times, want once", opened)
+			}
+			if first != tt.ctx || second != first {
+				t.Errorf("initAudio returned %p then %p, want %p both times", first, second, tt.ctx)
+			}
+			if audioErr != tt.err {
+				t.Errorf("audioErr is %v, want %v", audioErr, tt.err)
+			}
+		})
+	}
+}
+
+// TestResizeStress resizes the screen through degenerate, too small and
+// large sizes at each grid scale, split and whole, stepping and drawing
+// after every resize, so no size makes the fires and the screen disagree
+// enough to panic
+// TestCracklePanic breaks the crackling with a sample rate no clip can be
+// sized for, so the first crack panics in playWoodCrack. The goroutine should
+// record the panic and end, while the fire goes on burning.
+func TestCracklePanic(t *testing.T) {
+	savedCtx, savedRate, savedScreen, savedHearths, savedColors := audioCtx, audioRate, screen, hearths, colors
+	failuresMu.Lock()
+	savedFailures := failures
+	failures = nil
+	failuresMu.Unlock()
+	t.Cleanup(func() {
+		audioCtx, audioRate, screen, hearths, colors = savedCtx, savedRate, savedScreen, savedHearths, savedColors
+		crackleRate = atomic.Value{}
+		failuresMu.Lock()
+		failures = savedFailures
+		failuresMu.Unlock()
+	})
+	loadPalette()
+
+	sim := tcell.NewSimulationScreen("")
+	if err := sim.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Fini()
+	screen = sim
+	sim.SetSize(80, 24)
+	resize()
+
+	// Cracks so often that the first event is one, and soon
+	audioCtx, audioRate = new(oto.Context), -baseSampleRate
+	setCrackleScale(1e6)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		audioLoop(rand.New(rand.NewSource(1)), false, 0)
+	}()
+
+	timeout := time.After(5 * time.Second)
+	for stopped := false; !stopped; {
+		select {
+		case <-done:
+			stopped = true
+		case <-timeout:
+			t.Fatal("the crackling didn't stop after its panic")
+		default:
+			stepFires()
+			drawFrame()
+		}
+	}
+
+	failuresMu.Lock()
+	if len(failures) != 1 || !strings.HasPrefix(failures[0], "crackling stopped after a panic") {
+		t.Errorf("failures recorded %q, want the crackling's panic", failures)
+	}
+	failuresMu.Unlock()
+
+	for range 100 {
+		stepFires()
+		drawFrame()
+	}
+	heat := 0
+	for _, h := range hearths {
+		heat += h.Heat()
+	}
+	if heat == 0 {
+		t.Error("the fire went out after the crackling stopped")
+	}
+}
+
+func TestResizeStress(t *testing.T) {
+	savedScreen, savedHearths, savedScale, savedSplit, savedColors := screen, hearths, gridScale, splitMode, colors
+	savedReflection, savedMargin := reflectionRows, bottomMargin
+	t.Cleanup(func() {
+		screen, hearths, gridScale, splitMode, colors = savedScreen, savedHearths, savedScale, savedSplit, savedColors
+		reflectionRows, bottomMargin = savedReflection, savedMargin
+		resizeFrom = nil
+	})
+	loadPalette()
+
+	sim := tcell.NewSimulationScreen("")
+	if err := sim.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Fini()
+	screen = sim
+
+	sizes := [][2]int{
+		{0, 0}, {1, 1}, {80, 24}, {0, 24}, {80, 0},
+		{minFireWidth - 1, minFireHeight - 1}, {minFireWidth, minFireHeight},
+		{minFireWidth*maxGridScale - 1, minFireHeight * maxGridScale},
+		{7, 300}, {500, 3}, {400, 120}, {1, 1}, {81, 25},
+	}
+	for scale := 1; scale <= maxGridScale; scale++ {
+		for _, split := range []bool{false, true} {
+			// A reflection and a margin take rows off the fire's height
+			gridScale, splitMode = scale, split
+			reflectionRows, bottomMargin = 0, 0
+			if split {
+				reflectionRows, bottomMargin = 5, 2
+			}
+			for _, size := range sizes {
+				sim.SetSize(size[0], size[1])
+				resize()
+				for range 3 {
+					stepFires()
+					drawFrame()
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkCrack makes cracks the way playWoodCrack does, from a pooled
+// buffer handed back once it's played, with the allocations that takes
+func BenchmarkCrack(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	b.ReportAllocs()
+	for range b.N {
+		buf := getSampleBuffer(maxClipBytes())
+		synthCrack(*buf, rng, 0.35, 0.5)
+		samplePool.Put(buf)
+	}
+}
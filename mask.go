@@ -0,0 +1,29 @@
+package main
+
+import (
+	"image"
+	"os"
+
+	// Formats --mask can read, besides the PNG support export.go brings in
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// Shape from --mask to burn instead of logs (nil = procedural logs)
+var maskImage image.Image
+
+// loadMask reads the image at path for the fires to burn
+func loadMask(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return err
+	}
+	maskImage = img
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Runtime stats served by --metrics, updated by the render loop each frame
+type metrics struct {
+	FPS   float64 `json:"fps"`
+	Heat  int     `json:"heat"`
+	Logs  int     `json:"logs"`
+	Audio bool    `json:"audio"`
+	Wind  float64 `json:"wind"` // Push on the flames, the base wind and any gust together
+}
+
+var (
+	metricsEnabled bool
+	metricsMu      sync.Mutex
+	stats          metrics
+	lastFrame      time.Time
+)
+
+// startMetrics serves the stats as JSON over HTTP on addr
+func startMetrics(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", serveMetrics)
+	go http.Serve(ln, mux)
+
+	metricsEnabled = true
+	return nil
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	metricsMu.Lock()
+	snapshot := stats
+	metricsMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// recordMetrics updates the stats after a frame has been drawn
+func recordMetrics() {
+	if !metricsEnabled {
+		return
+	}
+
+	heat, logs := 0, 0
+	for _, h := range hearths {
+		heat += h.Heat()
+		logs += len(h.Logs())
+	}
+
+	now := time.Now()
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	// Smooth the frame rate so a single slow frame doesn't swing it
+	if dt := now.Sub(lastFrame).Seconds(); !lastFrame.IsZero() && dt > 0 {
+		if stats.FPS == 0 {
+			stats.FPS = 1 / dt
+		} else {
+			stats.FPS = stats.FPS*0.9 + (1/dt)*0.1
+		}
+	}
+	lastFrame = now
+
+	stats.Heat = heat
+	stats.Logs = logs
+	stats.Audio = audioCtx != nil
+	stats.Wind = wind
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestMetrics records the stats after steps of a storm over a steady wind
+// and checks what's served reports the wind as the flames feel it, base and
+// gust together, along with the fires
+func TestMetrics(t *testing.T) {
+	savedEnabled, savedStats, savedHearths := metricsEnabled, stats, hearths
+	savedBase, savedWind, savedStorm, savedRng, savedTick := baseWind, wind, stormMode, stormRng, tick
+	t.Cleanup(func() {
+		metricsEnabled, stats, hearths = savedEnabled, savedStats, savedHearths
+		baseWind, wind, stormMode, stormRng, tick = savedBase, savedWind, savedStorm, savedRng, savedTick
+	})
+	metricsEnabled, stats, hearths = true, metrics{}, nil
+	baseWind, stormMode, stormRng, tick = -0.5, true, nil, 0
+
+	served := func() metrics {
+		rec := httptest.NewRecorder()
+		serveMetrics(rec, httptest.NewRequest("GET", "/", nil))
+		var m metrics
+		if err := json.NewDecoder(rec.Body).Decode(&m); err != nil {
+			t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+		}
+		return m
+	}
+
+	calm, gusty := false, false
+	for tick = 1; tick <= 400 && !(calm && gusty); tick++ {
+		stepStorm()
+		recordMetrics()
+		m := served()
+		if m.Wind != wind {
+			t.Fatalf("tick %d: served wind %g, the flames feel %g", tick, m.Wind, wind)
+		}
+		if m.Heat != 0 || m.Logs != 0 {
+			t.Fatalf("tick %d: served heat %d and %d logs with no fires", tick, m.Heat, m.Logs)
+		}
+		if math.Abs(m.Wind-baseWind) < 1e-12 {
+			calm = true
+		} else {
+			gusty = true
+		}
+	}
+	if !calm || !gusty {
+		t.Errorf("saw a calm %v and a gust %v, want both", calm, gusty)
+	}
+}
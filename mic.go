@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"sync/atomic"
+)
+
+// There's no portable microphone capture without cgo, so --mic reads raw
+// signed 16-bit little-endian PCM from a file or pipe instead, as produced by
+// e.g. `arecord -f S16_LE -r 44100 | fireplace --mic -`. Channels don't
+// matter since only the loudness is used.
+
+// Smoothed input loudness from 0 to 1, shared with the render loop. Nil
+// means there is no input.
+var micLoudness atomic.Pointer[float64]
+
+// startMic opens the PCM source at path ("-" for stdin) and follows its
+// loudness in the background
+func startMic(path string) error {
+	var r io.ReadCloser = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		r = f
+	}
+
+	go func() {
+		defer stopOnPanic("microphone")
+		defer r.Close()
+		followMic(r)
+	}()
+	return nil
+}
+
+// followMic reads PCM from r until it ends, smoothing the RMS of each buffer
+// so the fire swells quickly with a sound and settles slowly after it. When
+// the input ends the fire goes back to burning normally.
+func followMic(r io.Reader) {
+	defer micLoudness.Store(nil)
+
+	buf := make([]byte, 2048)
+	level := 0.0
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n < 2 {
+			return
+		}
+
+		sum := 0.0
+		samples := n / 2
+		for i := range samples {
+			s := float64(int16(binary.LittleEndian.Uint16(buf[i*2:]))) / 32768
+			sum += s * s
+		}
+		rms := math.Sqrt(sum / float64(samples))
+
+		// Speech and music rarely get near full scale, so boost before
+		// clamping
+		target := math.Min(rms*4, 1)
+		if target > level {
+			level += (target - level) * 0.5
+		} else {
+			level += (target - level) * 0.05
+		}
+		l := level
+		micLoudness.Store(&l)
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// micIntensity scales the fire's fuel by the microphone loudness, from a low
+// smolder in silence to full heat when loud. It's 1 without any input.
+func micIntensity() float64 {
+	if v := micLoudness.Load(); v != nil {
+		return 0.3 + 0.7**v
+	}
+	return 1
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sync"
+
+	"github.com/hajimehoshi/oto/v2"
+)
+
+// mixer is the one stream oto plays: the rumble with every clip that's
+// still playing summed on top. A player per clip meant player churn, and on
+// backends with few players overlapping cracks could cut each other off.
+type mixer struct {
+	mu     sync.Mutex
+	rumble *RumbleReader
+	clips  []clip
+	failed bool // Set after a panic, from then on only the rumble plays
+}
+
+// clip is a short sound being mixed in. Its envelope, the fade of a sizzle
+// or the decay of a crack, is already in its samples.
+type clip struct {
+	buf *[]byte // Pooled 16-bit stereo samples
+	pos int     // Bytes already mixed
+}
+
+var (
+	audioMixer  *mixer
+	audioPlayer oto.Player // Kept so the stream is never collected while it plays
+)
+
+// How much audio the player buffers ahead. Clips start at the next read, so
+// this is how late a crack can sound after it's played.
+const mixAheadSamples = baseSampleRate / 20
+
+// startMixer starts the output stream with the rumble drawn from rng
+func startMixer(rng *rand.Rand) {
+	if audioCtx == nil {
+		return
+	}
+
+	// The side generator counts down from the seed where the others count up
+	rumble := &RumbleReader{rng: rng, width: rumbleWidth, sideRng: rand.New(rand.NewSource(seed - 2)), every: 1}
+	if rumbleQuality == "low" {
+		rumble.every = lowRumbleEvery
+	}
+	audioMixer = &mixer{rumble: rumble}
+	audioPlayer = audioCtx.NewPlayer(audioMixer)
+	if s, ok := audioPlayer.(oto.BufferSizeSetter); ok {
+		s.SetBufferSize(int(float64(mixAheadSamples)/rateRatio()) * 4)
+	}
+	audioPlayer.Play()
+}
+
+// add starts a pooled clip playing. The buffer goes back to the pool once
+// it's been mixed in full.
+func (m *mixer) add(buf *[]byte) {
+	m.mu.Lock()
+	m.clips = append(m.clips, clip{buf: buf})
+	m.mu.Unlock()
+}
+
+func (m *mixer) Read(p []byte) (n int, err error) {
+	p = p[:len(p)/4*4]
+	m.rumble.Read(p)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Like the rumble, this runs on oto's goroutine, where a panic can't be
+	// left to the other guards
+	if m.failed {
+		return len(p), nil
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			m.failed = true
+			recordFailure("audio mixer", v)
+			n, err = len(p), nil
+		}
+	}()
+
+	live := m.clips[:0]
+	for _, c := range m.clips {
+		samples := *c.buf
+		n := min(len(p), len(samples)-c.pos)
+		for i := 0; i < n; i += 2 {
+			mixed := int32(int16(binary.LittleEndian.Uint16(p[i:]))) +
+				int32(int16(binary.LittleEndian.Uint16(samples[c.pos+i:])))
+			binary.LittleEndian.PutUint16(p[i:], uint16(min(max(mixed, -32768), 32767)))
+		}
+		c.pos += n
+		if c.pos < len(samples) {
+			live = append(live, c)
+		} else {
+			samplePool.Put(c.buf)
+		}
+	}
+	clear(m.clips[len(live):])
+	m.clips = live
+	return len(p), nil
+}
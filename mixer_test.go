@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// newTestRumble returns a rumble from fixed seeds, the same every call
+func newTestRumble() *RumbleReader {
+	return &RumbleReader{rng: rand.New(rand.NewSource(1)), sideRng: rand.New(rand.NewSource(2)), every: 1}
+}
+
+// TestMixerPanic mixes in a clip cut off partway through a sample, which
+// overruns the clip and panics. The mixer has to keep the panic from oto's
+// goroutine, record it once and go on playing the rumble by itself.
+func TestMixerPanic(t *testing.T) {
+	failuresMu.Lock()
+	saved := failures
+	failures = nil
+	failuresMu.Unlock()
+	t.Cleanup(func() {
+		failuresMu.Lock()
+		failures = saved
+		failuresMu.Unlock()
+	})
+
+	m := &mixer{rumble: newTestRumble()}
+	broken := make([]byte, 3)
+	m.add(&broken)
+
+	// The same rumble without the mixer, to check what keeps playing
+	alone := newTestRumble()
+	want := make([]byte, 4096)
+
+	p := make([]byte, 4096)
+	for i := range 3 {
+		n, err := m.Read(p)
+		if n != len(p) || err != nil {
+			t.Fatalf("read %d returned %d, %v, want %d, nil", i+1, n, err, len(p))
+		}
+		alone.Read(want)
+		if i > 0 && !bytes.Equal(p, want) {
+			t.Errorf("read %d after the panic isn't the rumble alone", i+1)
+		}
+	}
+
+	if !m.failed {
+		t.Error("the mixer didn't note that it failed")
+	}
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	if len(failures) != 1 {
+		t.Errorf("%d failures recorded, want 1: %q", len(failures), failures)
+	}
+}
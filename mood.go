@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// A mood bundles how much fuel the fire gets, how much of the log bed burns,
+// how tall the flames reach and how often the wood cracks
+type mood struct {
+	name                  string
+	intensity, span       float64
+	flameHeight, crackles float64
+}
+
+// Moods 'n' steps through, in order, coming back round to the first
+var moods = []mood{
+	{"roaring", 1.6, 0.95, 1.5, 2},
+	{"steady", 1, 0.8, 1, 1},
+	{"coals", 0.7, 0.6, 0.7, 0.4},
+	{"embers", 0.35, 0.5, 0.5, 0.15},
+	{"out", 0, 0.5, 0.5, 0},
+}
+
+// Frames a change of mood is eased over (about a second at 20 FPS)
+const moodFrames = 20
+
+var (
+	moodIndex = -1         // Mood last chosen, so the first press starts the rotation (-1 = none)
+	moodFrom  mood         // Settings the current change started from
+	moodFrame = moodFrames // Frames into the change (moodFrames when there's none)
+)
+
+// nextMood starts easing into the next mood
+func nextMood() {
+	setMood((moodIndex + 1) % len(moods))
+}
+
+// setMood starts easing from the settings as they stand into mood i, and
+// names it on screen
+func setMood(i int) {
+	moodIndex = i
+	moodFrom = mood{"", intensity, fireSpanRatio, flameHeight, crackleScale()}
+	moodFrame = 0
+	notify("mood: " + moods[i].name)
+}
+
+// moodNamed finds a mood's index by name
+func moodNamed(name string) (int, error) {
+	for i, m := range moods {
+		if m.name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mood %q", name)
+}
+
+// stepMood moves the settings a frame further into the chosen mood, easing
+// in and out so the fire swells or dies down rather than snapping
+func stepMood() {
+	if moodFrame >= moodFrames {
+		return
+	}
+	moodFrame++
+
+	to := moods[moodIndex]
+	t := float64(moodFrame) / moodFrames
+	t = (1 - math.Cos(t*math.Pi)) / 2
+	lerp := func(a, b float64) float64 { return a + (b-a)*t }
+
+	intensity = lerp(moodFrom.intensity, to.intensity)
+	fireSpanRatio = lerp(moodFrom.span, to.span)
+	flameHeight = lerp(moodFrom.flameHeight, to.flameHeight)
+	setCrackleScale(lerp(moodFrom.crackles, to.crackles))
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// A palette file lists hex RGB colors from coldest to hottest, separated by
+// spaces, commas or newlines, e.g.
+//
+//	// a green fire
+//	#071007, #0F2F07, #1F4F0F
+//	0x3F8F1F 7FCF3F
+//
+// The leading '#' or 0x is optional and anything after // on a line is a
+// comment. It's drawn under the palette name "file".
+
+// Name --palette-file's colors are looked up by
+const filePaletteName = "file"
+
+var (
+	paletteFile  string   // Path given with --palette-file ("" = none)
+	watchPalette bool     // Whether to reload the palette file when it changes
+	filePalette  []uint32 // Colors last read from the palette file
+	dumpPalette  string   // Where the p key saves the palette ("" = a new file each time)
+)
+
+// How often --watch checks the palette file for changes
+const paletteWatchInterval = 500 * time.Millisecond
+
+// readPaletteFile parses the palette file at path
+func readPaletteFile(path string) ([]uint32, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var hexes []uint32
+	for n, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})
+		for _, field := range fields {
+			hex := strings.TrimPrefix(strings.TrimPrefix(field, "#"), "0x")
+			v, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil || len(hex) != 6 {
+				return nil, fmt.Errorf("%s:%d: %q is not a hex color", path, n+1, field)
+			}
+			hexes = append(hexes, uint32(v))
+		}
+	}
+
+	if len(hexes) == 0 {
+		return nil, fmt.Errorf("%s: no colors", path)
+	}
+	if len(hexes) > 36 {
+		return nil, fmt.Errorf("%s: %d colors, at most 36 fit the heat range", path, len(hexes))
+	}
+	return hexes, nil
+}
+
+// loadPaletteFile reads --palette-file, if given, and selects its colors. On
+// an error the previous colors are kept.
+func loadPaletteFile() error {
+	if paletteFile == "" {
+		return nil
+	}
+	hexes, err := readPaletteFile(paletteFile)
+	if err != nil {
+		return err
+	}
+	filePalette = hexes
+	paletteName = filePaletteName
+	return nil
+}
+
+// paletteUpdate is the result of re-reading a changed palette file
+type paletteUpdate struct {
+	hexes []uint32
+	err   error
+}
+
+// watchPaletteFile polls the palette file at path and sends its new contents
+// whenever its size or modification time changes. Polling needs nothing
+// platform-specific and catches editors that save by replacing the file.
+func watchPaletteFile(path string, updates chan<- paletteUpdate) {
+	defer stopOnPanic("palette watcher")
+
+	var lastMod time.Time
+	var lastSize int64
+	if info, err := os.Stat(path); err == nil {
+		lastMod, lastSize = info.ModTime(), info.Size()
+	}
+
+	for range time.Tick(paletteWatchInterval) {
+		info, err := os.Stat(path)
+		if err != nil {
+			// Mid-save the file can briefly be missing; wait for it
+			continue
+		}
+		if info.ModTime().Equal(lastMod) && info.Size() == lastSize {
+			continue
+		}
+		lastMod, lastSize = info.ModTime(), info.Size()
+
+		hexes, err := readPaletteFile(path)
+		updates <- paletteUpdate{hexes, err}
+	}
+}
+
+// applyPaletteUpdate switches to a re-read palette file, or reports why it
+// couldn't be used and keeps the current colors
+func applyPaletteUpdate(u paletteUpdate) {
+	if u.err != nil {
+		logger.Warn("palette file not reloaded", "err", u.err)
+		showStatus(u.err.Error())
+		return
+	}
+	filePalette = u.hexes
+	if paletteName == filePaletteName {
+		loadPalette()
+	}
+	logger.Info("palette file reloaded", "path", paletteFile, "colors", len(u.hexes))
+	showStatus("palette reloaded")
+}
+
+// writePaletteFile saves heat colors 1 to 36 of c to path in the palette
+// file format, six to a line. Heat 0 is always black, so it's left out.
+func writePaletteFile(path string, c []tcell.Color) error {
+	var b strings.Builder
+	b.WriteString("// saved by fireplace, coldest to hottest\n")
+	for i := 1; i < len(c); i++ {
+		r, g, bl := c[i].RGB()
+		fmt.Fprintf(&b, "#%02X%02X%02X", r, g, bl)
+		if i%6 == 0 {
+			b.WriteString("\n")
+		} else {
+			b.WriteString(", ")
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// savePalette writes the colors the fire is drawn with right now, partway
+// through a crossfade and turned by --enchanted as they are, to
+// --dump-palette or a timestamped file. Loading it with --palette-file
+// shows the same colors, as long as --temperature isn't applied again.
+func savePalette() {
+	name := dumpPalette
+	if name == "" {
+		name = time.Now().Format("fireplace-20060102-150405.palette")
+	}
+	if err := writePaletteFile(name, firePalette()); err != nil {
+		showStatus("couldn't save the palette: " + err.Error())
+		return
+	}
+	showStatus("palette saved to " + name)
+}
@@ -0,0 +1,26 @@
+package main
+
+import "sync/atomic"
+
+// Whether pausing the fire silences it too. With it off the crackling plays
+// on over a still fire, and the sound is only ever stopped with mute.
+var pauseAudio = true
+
+// Whether the simulation is paused, shared with the audio goroutines. The
+// sound has a state of its own in muted, so the two can be held separately.
+var paused atomic.Bool
+
+// togglePause freezes the fire on its current frame or sets it going again
+func togglePause() {
+	paused.Store(!paused.Load())
+	if paused.Load() {
+		notify("paused")
+	} else {
+		notify("resumed")
+	}
+}
+
+// audioPaused reports whether the sound is held by a pause of the fire
+func audioPaused() bool {
+	return pauseAudio && paused.Load()
+}
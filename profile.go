@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// writeMemProfile writes a heap profile to path. It runs as main returns, so
+// a profile is written however the fire was put out.
+func writeMemProfile(path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "--memprofile:", err)
+		return
+	}
+	defer f.Close()
+
+	// Collect first so the profile shows what's still held, not garbage
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		fmt.Fprintln(os.Stderr, "--memprofile:", err)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+// safeMode is set by --safe, for terminals nothing is known about, such as
+// on CI or an unfamiliar SSH host. It turns on plain characters with no color
+// escapes, as --ascii, and no audio, as --silent. It also stops anything else
+// that writes to or reads from the terminal beyond drawing: --adapt-bg's
+// background query, the window title and the OSC 52 clipboard, so 'y' saves
+// the frame to a file. The mouse is never captured in any mode. Safe mode
+// wins over a config file, a theme or other flags.
+var safeMode bool
+
+// applySafeMode overrides whatever settings --safe rules out
+func applySafeMode() {
+	if !safeMode {
+		return
+	}
+	asciiMode = true
+	colorMode = "auto"
+	silentMode = true
+	adaptBg = false
+}
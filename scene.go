@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/donnybeelo/fireplace/fireplace"
+)
+
+// A saved scene: every setting in force plus the exact logs of each fire, so
+// a hand-tuned fireplace comes back the same even if log generation changes
+type savedScene struct {
+	Settings map[string]string `json:"settings"`
+	Hearths  []sceneHearth     `json:"hearths"`
+}
+
+type sceneHearth struct {
+	Width  int        `json:"width"`
+	Height int        `json:"height"`
+	Logs   []sceneLog `json:"logs"`
+}
+
+// sceneLog is a log by the ends of its axis, as fireplace.NewLog takes them
+type sceneLog struct {
+	X1 float64 `json:"x1"`
+	Y1 float64 `json:"y1"`
+	X2 float64 `json:"x2"`
+	Y2 float64 `json:"y2"`
+	R  float64 `json:"r"`
+}
+
+// Flags that say what to do with this run rather than how the fire looks,
+// which a scene leaves out
+var sceneSkip = map[string]bool{
+	"scene": true, "save-scene": true, "config": true, "seed": true,
+	"once": true, "duration": true, "sleep": true, "demo": true,
+	"dump-state": true, "dump-palette": true, "mask": true, "mic": true, "control-socket": true,
+	"metrics": true, "serial-light": true, "events-out": true, "light-format": true, "list-palettes": true, "version": true, "png-dir": true, "frames": true,
+	"cell": true, "bench-frames": true,
+	"verbose": true, "log-file": true, "memprofile": true,
+	"silent": true, "s": true, "pause-audio": true, "quit-key": true, "safe": true, "inline": true,
+}
+
+// Flags the program rewrites to suit the terminal, which a scene saves as
+// they were given
+var sceneAsGiven = map[string]string{"ascii": ""}
+
+// noteSceneSettings records the flags in sceneAsGiven before anything
+// rewrites them
+func noteSceneSettings() {
+	for name := range sceneAsGiven {
+		sceneAsGiven[name] = flag.Lookup(name).Value.String()
+	}
+}
+
+// Scene loaded with --scene, whose logs the fires are built on (nil = none)
+var scene *savedScene
+
+var sceneNameRE = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// scenePath returns the file the scene called name is kept in, under the
+// config directory
+func scenePath(name string) (string, error) {
+	if !sceneNameRE.MatchString(name) {
+		return "", fmt.Errorf("scene name %q must be letters, digits, - and _", name)
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fireplace", "scenes", name+".json"), nil
+}
+
+// saveScene writes the current settings and the logs of every fire as the
+// scene called name
+func saveScene(name string) error {
+	path, err := scenePath(name)
+	if err != nil {
+		return err
+	}
+
+	s := savedScene{Settings: map[string]string{}}
+	flag.VisitAll(func(f *flag.Flag) {
+		if given, ok := sceneAsGiven[f.Name]; ok {
+			s.Settings[f.Name] = given
+		} else if !sceneSkip[f.Name] {
+			s.Settings[f.Name] = f.Value.String()
+		}
+	})
+	for _, h := range hearths {
+		w, ht := h.Size()
+		sh := sceneHearth{Width: w, Height: ht}
+		for _, l := range h.Logs() {
+			x1, y1, x2, y2 := l.Ends()
+			sh.Logs = append(sh.Logs, sceneLog{x1, y1, x2, y2, l.R})
+		}
+		s.Hearths = append(s.Hearths, sh)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// loadScene reads the scene called name and applies its settings as if they
+// had been given on the command line, except where a flag really was
+func loadScene(name string) error {
+	path, err := scenePath(name)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no scene called %q in %s", name, filepath.Dir(path))
+	}
+	if err != nil {
+		return err
+	}
+
+	var s savedScene
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for name, value := range s.Settings {
+		if cliFlags[name] || sceneSkip[name] || flag.Lookup(name) == nil {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		cliFlags[name] = true
+	}
+	scene = &s
+	return nil
+}
+
+// sceneLogs returns the saved logs for fire i at w by h, or nil to generate
+// them. In a region of another size they keep to the middle of the floor.
+func sceneLogs(i, w, h int) []fireplace.Log {
+	if scene == nil || i >= len(scene.Hearths) {
+		return nil
+	}
+	sh := scene.Hearths[i]
+
+	dx := float64(w-sh.Width) / 2
+	dy := float64(h - sh.Height)
+	if direction == "down" {
+		dy = 0
+	}
+	logs := make([]fireplace.Log, 0, len(sh.Logs))
+	for _, l := range sh.Logs {
+		logs = append(logs, fireplace.NewLog(l.X1+dx, l.Y1+dy, l.X2+dx, l.Y2+dy, l.R))
+	}
+	return logs
+}
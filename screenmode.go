@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/terminfo"
+	"golang.org/x/term"
+)
+
+// inlineRows is set by --inline: the fire burns in this many rows at the
+// bottom of the terminal's main screen, instead of on the alternate screen
+// (0 = alternate screen)
+var inlineRows int
+
+// xterm's switch to and from the alternate screen buffer
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+)
+
+// prepareTerminal gets the terminal ready before tcell takes it over, and
+// returns what to undo once tcell has let it go.
+//
+// tcell switches to the alternate screen with the terminfo entry's smcup, so
+// an entry without one would leave the fire scrolling the user's history.
+// Then the xterm sequence is sent instead, which nearly every emulator
+// understands. Inline, tcell stays on the main screen, but it still clears
+// it on start, so everything on screen is first scrolled up into the
+// scrollback to keep it.
+func prepareTerminal() (restore func()) {
+	if !isTerminal(os.Stdout) {
+		return func() {}
+	}
+
+	if inlineRows > 0 {
+		os.Setenv("TCELL_ALTSCREEN", "disable")
+		if _, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			fmt.Print(strings.Repeat("\n", h))
+		}
+		return func() {}
+	}
+
+	ti, err := terminfo.LookupTerminfo(os.Getenv("TERM"))
+	if err != nil || ti.EnterCA != "" || os.Getenv("TCELL_ALTSCREEN") == "disable" {
+		return func() {}
+	}
+	logger.Info("terminfo has no alternate screen, asking for xterm's", "term", os.Getenv("TERM"))
+	fmt.Print(enterAltScreen)
+	return func() { fmt.Print(exitAltScreen) }
+}
+
+// drawTop returns the first screen row the fire and its overlays use: the
+// top of the screen, or inline the top of the fire's rows
+func drawTop() int {
+	if inlineRows == 0 {
+		return 0
+	}
+	_, h := screen.Size()
+	return max(h-inlineRows, 0)
+}
+
+// clearScreen clears the rows drawTop on to style. Inline, whatever is above
+// is left alone.
+func clearScreen(style tcell.Style) {
+	screen.SetStyle(style)
+	if inlineRows == 0 {
+		screen.Clear()
+		return
+	}
+
+	w, h := screen.Size()
+	for y := drawTop(); y < h; y++ {
+		for x := range w {
+			screen.SetContent(x, y, ' ', nil, style)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Whether the terminal can stop and continue the program with job control
+const canSuspend = true
+
+// notifyReload delivers SIGHUP to c so the config can be re-read
+func notifyReload(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGHUP)
+}
+
+// notifySuspend delivers SIGTSTP to c, so the screen and sound are put away
+// before the program stops rather than left as they were
+func notifySuspend(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGTSTP)
+}
+
+// stopProcess stops the program until it's sent SIGCONT. SIGSTOP can't be
+// caught, so unlike SIGTSTP it doesn't come back to notifySuspend.
+func stopProcess() {
+	syscall.Kill(syscall.Getpid(), syscall.SIGSTOP)
+}
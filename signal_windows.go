@@ -0,0 +1,15 @@
+package main
+
+import "os"
+
+// Windows has no job control to stop the program with
+const canSuspend = false
+
+// notifyReload is a no-op since Windows has no SIGHUP
+func notifyReload(c chan<- os.Signal) {}
+
+// notifySuspend is a no-op since Windows has no SIGTSTP
+func notifySuspend(c chan<- os.Signal) {}
+
+// stopProcess is never called on Windows
+func stopProcess() {}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Snapshot of the generated scene, written by --dump-state for bug reports
+type sceneState struct {
+	Hearths []hearthState `json:"hearths"`
+}
+
+type hearthState struct {
+	X      int        `json:"x"`
+	Y      int        `json:"y"`
+	Width  int        `json:"width"`
+	Height int        `json:"height"`
+	Logs   []logState `json:"logs"`
+}
+
+type logState struct {
+	ID     int     `json:"id"`
+	MidX   float64 `json:"midX"`
+	MidY   float64 `json:"midY"`
+	Angle  float64 `json:"angle"`
+	Length float64 `json:"length"`
+	R      float64 `json:"r"`
+}
+
+// dumpState writes the current log layout of every fire to path as JSON
+func dumpState(path string) error {
+	var state sceneState
+	for _, f := range hearths {
+		w, h := f.Size()
+		hs := hearthState{X: f.x, Y: f.y, Width: w, Height: h}
+		for _, l := range f.Logs() {
+			hs.Logs = append(hs.Logs, logState{
+				ID: l.ID, MidX: l.MidX, MidY: l.MidY,
+				Angle: l.Angle, Length: l.Length, R: l.R,
+			})
+		}
+		state.Hearths = append(state.Hearths, hs)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,81 @@
+package main
+
+import "time"
+
+// How long a status message stays on screen
+const statusDuration = 3 * time.Second
+
+var (
+	statusText  string    // Message shown along the bottom of the screen
+	statusUntil time.Time // When the message disappears
+)
+
+// showStatus flashes a one-line message at the bottom of the screen
+func showStatus(text string) {
+	statusText = text
+	statusUntil = time.Now().Add(statusDuration)
+}
+
+// drawStatus draws the current status message, if it hasn't expired
+func drawStatus() {
+	if statusText == "" || time.Now().After(statusUntil) {
+		return
+	}
+
+	screenW, screenH := screen.Size()
+	x := 0
+	for _, r := range " " + statusText + " " {
+		if x >= screenW {
+			break
+		}
+		screen.SetContent(x, screenH-1, r, nil, helpStyle(x, screenH-1))
+		x++
+	}
+}
+
+// Frames a notification stays up, about 1.5 seconds at 20 FPS, and how many
+// of them it spends fading out
+const (
+	notifyFrames     = 30
+	notifyFadeFrames = 10
+)
+
+var (
+	notifyText  string // Confirmation of the last control used
+	notifyFrame int    // Frames left until it's gone
+)
+
+// notify briefly confirms a change made with a control, such as "muted" or
+// "palette: cb", in the top-right corner. It's shorter lived than a status
+// message and is replaced by the next control rather than queued.
+func notify(text string) {
+	notifyText = text
+	notifyFrame = notifyFrames
+}
+
+// drawNotify draws the current notification, fading it into its panel over
+// its last few frames, and counts the frame off
+func drawNotify() {
+	if notifyFrame <= 0 {
+		return
+	}
+	notifyFrame--
+
+	text := []rune(" " + notifyText + " ")
+	screenW, _ := screen.Size()
+	left := max(screenW-len(text)-1, 0)
+	top := drawTop()
+	fade := min(float64(notifyFrame)/notifyFadeFrames, 1)
+	for i, r := range text {
+		x := left + i
+		if x >= screenW {
+			break
+		}
+		style := helpStyle(x, top)
+		if !asciiMode && fade < 1 {
+			fg, bg, _ := style.Decompose()
+			style = style.Foreground(mixColors(bg, fg, fade))
+		}
+		screen.SetContent(x, top, r, nil, style)
+	}
+}
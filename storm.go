@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	stormMode      bool       // Whether gusts of wind sweep the fire now and then
+	stormIntensity = 1.0      // How violent the gusts are, from 0 to 2
+	stormRng       *rand.Rand // Times, directions and strengths of the gusts
+)
+
+// Most a gust at --storm-intensity 1 pushes the flames sideways, in cells per
+// sub-pixel row at the tips
+const maxGust = 2.0
+
+// Strongest steady --wind either way, as hard as a gust at its peak
+const maxWind = maxGust
+
+// Gust timing in simulation ticks, 20 a second at the normal time scale. A
+// calm of a few seconds always follows a gust, so the flames have time to
+// stand straight again.
+const (
+	minGustTicks = 40  // Shortest gust, rise and fall included
+	maxGustTicks = 70  // Longest gust
+	minCalmTicks = 60  // Shortest calm between gusts
+	maxCalmTicks = 160 // Longest calm
+)
+
+var (
+	baseWind  float64 // Steady push set with --wind, which gusts blow on top of
+	wind      float64 // Sideways push on the flames this tick, negative to the left
+	gustStart int     // Tick the current or next gust begins
+	gustTicks int     // How long it lasts
+	gustPeak  float64 // Push at its height
+)
+
+// How strong the wind is for the rumble, from 0 (calm) to 1 (the strongest
+// gust), shared with the audio goroutines. An unset value means calm.
+var gustLevel atomic.Value
+
+func gustStrength() float64 {
+	if v, ok := gustLevel.Load().(float64); ok {
+		return v
+	}
+	return 0
+}
+
+// stepStorm blows the wind for the current tick. Each gust swells and dies
+// away smoothly in one direction, then a calm of random length passes before
+// the next, with only the base wind blowing.
+func stepStorm() {
+	wind = baseWind
+	if !stormMode {
+		return
+	}
+	if stormRng == nil {
+		stormRng = rand.New(rand.NewSource(seed - 3))
+		scheduleGust(tick)
+	}
+
+	t := float64(tick-gustStart) / float64(gustTicks)
+	if t >= 1 {
+		scheduleGust(tick)
+		t = float64(tick-gustStart) / float64(gustTicks)
+	}
+	if tick == gustStart {
+		emitEvent(fireEvent{Type: "gust", Wind: gustPeak, Seconds: (time.Duration(gustTicks) * frameTime).Seconds()})
+	}
+	gust := 0.0
+	if t > 0 {
+		gust = gustPeak * math.Sin(t*math.Pi)
+	}
+	wind += gust
+	gustLevel.Store(math.Abs(gust) / (maxGust * 2))
+}
+
+// scheduleGust picks the next gust to begin after a calm from tick now
+func scheduleGust(now int) {
+	gustStart = now + minCalmTicks + stormRng.Intn(maxCalmTicks-minCalmTicks+1)
+	gustTicks = minGustTicks + stormRng.Intn(maxGustTicks-minGustTicks+1)
+	gustPeak = (0.4 + 0.6*stormRng.Float64()) * maxGust * stormIntensity
+	if stormRng.Intn(2) == 0 {
+		gustPeak = -gustPeak
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "time"
+
+// suspend stops the program for the shell, as Ctrl+Z does outside the raw
+// mode the screen runs in, and picks up where it left off once it's
+// continued. The sound is stopped first so a crackle isn't left looping
+// from the device's buffer, then the terminal is handed back in its
+// normal state. stopProcess returns once the shell continues the program.
+//
+// Between frames nothing else touches the screen, and the process stops
+// as a whole, so the audio goroutines are frozen along with it. The
+// ticker doesn't queue up the time away, so the fire carries on from the
+// frame it stopped on.
+func suspend() {
+	if !canSuspend {
+		return
+	}
+	logger.Info("suspended")
+	if audioCtx != nil {
+		if err := audioCtx.Suspend(); err != nil {
+			logger.Warn("couldn't suspend the audio", "err", err)
+		}
+	}
+	w, h := screen.Size()
+	if err := screen.Suspend(); err != nil {
+		logger.Warn("couldn't suspend the screen", "err", err)
+	}
+	start := time.Now()
+
+	stopProcess()
+
+	if err := screen.Resume(); err != nil {
+		logger.Warn("couldn't resume the screen", "err", err)
+	}
+	// Whatever ran in the terminal meanwhile has drawn over the frame
+	screen.Sync()
+	if sw, sh := screen.Size(); sw != w || sh != h {
+		resize()
+	}
+	if audioCtx != nil {
+		if err := audioCtx.Resume(); err != nil {
+			logger.Warn("couldn't resume the audio", "err", err)
+		}
+	}
+	logger.Info("resumed", "after", time.Since(start).Round(time.Second))
+}
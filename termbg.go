@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// adaptBg is whether --adapt-bg picks the palette to suit the terminal's
+// background
+var adaptBg bool
+
+// Palette --adapt-bg switches to on a light background, also selectable as
+// "daylight": deeper, saturated
+// reds and oranges that stop short of the pale yellows a light background
+// would swallow
+var daylightPalette = []uint32{
+	0x1F0702, 0x280802, 0x310902, 0x3A0A03, 0x430B03, 0x4C0C03, 0x560E03, 0x5F0F03,
+	0x681004, 0x711104, 0x7A1204, 0x801504, 0x861904, 0x8C1C04, 0x921F05, 0x982305,
+	0x9E2605, 0xA42905, 0xAA2D05, 0xB03006, 0xB63306, 0xBC3706, 0xC23A06, 0xC43F06,
+	0xC74407, 0xC94807, 0xCB4D07, 0xCE5208, 0xD05708, 0xD25B08, 0xD46008, 0xD76509,
+	0xD96A09, 0xDB6E09, 0xDE730A, 0xE0780A,
+}
+
+// How long to wait for the terminal to answer the background query
+const bgQueryTimeout = 200 * time.Millisecond
+
+var (
+	bgReply  = regexp.MustCompile(`\x1b\]11;rgb:([0-9a-fA-F]{1,4})/([0-9a-fA-F]{1,4})/([0-9a-fA-F]{1,4})`)
+	da1Reply = regexp.MustCompile(`\x1b\[\?[0-9;]*c`)
+)
+
+// queryBackground asks the terminal for its background color with OSC 11,
+// returning each channel from 0 to 1, and whether it answered. A device
+// attributes query follows it, which every terminal answers, so one that
+// ignores OSC 11 is found out without waiting for the timeout and leaves no
+// late reply behind to be read as key presses.
+func queryBackground() (r, g, b float64, ok bool) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	defer tty.Close()
+
+	// Without a deadline an unanswered query would block forever. Fd would
+	// put the tty back into blocking mode, losing the deadline, so the raw
+	// mode is set through SyscallConn instead.
+	if err := tty.SetReadDeadline(time.Now().Add(bgQueryTimeout)); err != nil {
+		return 0, 0, 0, false
+	}
+	conn, err := tty.SyscallConn()
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	var fd int
+	var state *term.State
+	conn.Control(func(f uintptr) {
+		fd = int(f)
+		state, err = term.MakeRaw(fd)
+	})
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	defer term.Restore(fd, state)
+
+	if _, err := tty.WriteString("\x1b]11;?\x1b\\\x1b[c"); err != nil {
+		return 0, 0, 0, false
+	}
+
+	var reply []byte
+	buf := make([]byte, 64)
+	for !da1Reply.Match(reply) {
+		n, err := tty.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+
+	m := bgReply.FindSubmatch(reply)
+	if m == nil {
+		return 0, 0, 0, false
+	}
+	return channel(m[1]), channel(m[2]), channel(m[3]), true
+}
+
+// channel scales a color channel of one to four hex digits, as X11 color
+// specs give them, to the range 0 to 1
+func channel(hex []byte) float64 {
+	v, _ := strconv.ParseUint(string(hex), 16, 16)
+	return float64(v) / float64(uint64(1)<<(4*len(hex))-1)
+}
+
+// adaptToBackground switches to the daylight palette if the terminal reports
+// a light background, unless a palette was chosen some other way. A terminal
+// that doesn't answer keeps the usual palette.
+func adaptToBackground() {
+	r, g, b, ok := queryBackground()
+	if !ok {
+		logger.Info("terminal background unknown")
+		return
+	}
+
+	luminance := 0.2126*r + 0.7152*g + 0.0722*b
+	light := luminance > 0.5
+	logger.Info("terminal background", "luminance", luminance, "light", light)
+	if !light || paletteChosen() {
+		return
+	}
+	paletteName = "daylight"
+	loadPalette()
+}
+
+// paletteChosen reports whether the palette was picked on the command line,
+// in the config file or by the theme, rather than left at its default
+func paletteChosen() bool {
+	if cliFlags["palette"] || fileFlags["palette"] || paletteFile != "" {
+		return true
+	}
+	for _, t := range themes {
+		if _, ok := t.settings["palette"]; ok && t.name == themeName {
+			return true
+		}
+	}
+	return false
+}
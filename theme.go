@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Built-in themes for --theme. Each bundles settings in config file form,
+// which apply beneath anything set on the command line or in the config file.
+var themes = []struct {
+	name     string
+	settings map[string]string
+}{
+	{"cozy-cabin", map[string]string{
+		"palette": "doom", "temperature": "0.3", "ambient": "true",
+		"smooth": "true", "texture": "1.2",
+		"layout": "hearth", "intensity": "0.9", "fire-span": "0.7", "crackle-rate": "0.8",
+	}},
+	{"blue-hell", map[string]string{
+		"palette": "doom", "temperature": "-1", "heat-sources": "5",
+		"layout": "teepee", "intensity": "1.4", "fire-span": "0.9", "crackle-rate": "1.5",
+		"glyph-ramp": " .:*#@",
+	}},
+	{"dying-embers", map[string]string{
+		"coals": "true", "floor": "6", "max-heat": "28",
+		"temperature": "0.5", "texture": "1.5",
+		"layout": "hearth", "intensity": "0.5", "fire-span": "0.6", "crackle-rate": "0.3",
+	}},
+	{"roaring-bonfire", map[string]string{
+		"heat-sources": "6", "max-heat": "36", "logs": "160",
+		"layout": "hearth", "intensity": "1.8", "fire-span": "0.95", "crackle-rate": "2",
+		"glyph-ramp": " ░▒▓█",
+	}},
+}
+
+// themeName is the theme selected with --theme ("" for none)
+var themeName string
+
+// applyTheme sets the selected theme's settings, leaving alone any that were
+// given explicitly on the command line or in the config file
+func applyTheme() error {
+	if themeName == "" {
+		return nil
+	}
+
+	for _, t := range themes {
+		if t.name != themeName {
+			continue
+		}
+		for name, value := range t.settings {
+			if cliFlags[name] || fileFlags[name] {
+				continue
+			}
+			if err := flag.Set(name, value); err != nil {
+				return fmt.Errorf("--theme %s: %v", themeName, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("--theme: unknown theme %q", themeName)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build details, set at build time with e.g.
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%F)"
+//
+// Whatever isn't set is filled in from what the Go toolchain recorded, if
+// anything.
+var (
+	version   = ""
+	commit    = ""
+	buildDate = ""
+)
+
+// versionString describes this build in one line for --version and bug
+// reports
+func versionString() string {
+	v, c, d := version, commit, buildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && c == "":
+				c = s.Value
+			case s.Key == "vcs.time" && d == "":
+				d = s.Value
+			}
+		}
+	}
+	if v == "" {
+		v = "dev"
+	}
+	if len(c) > 12 {
+		c = c[:12]
+	}
+	if c == "" {
+		c = "unknown commit"
+	}
+	if d == "" {
+		d = "unknown date"
+	}
+	return fmt.Sprintf("fireplace %s (%s, built %s, %s)", v, c, d, runtime.Version())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var (
+	visualizerMode     bool    // Whether cracks warm and brighten the palette
+	visualizerStrength = 0.5   // How far the loudest crack pushes the colors, from 0 to 1
+	surge              float64 // How far the palette is pushed this frame, fading between cracks
+)
+
+// Share of the surge left after each frame, so a crack's glow fades in
+// about a second
+const surgeFade = 0.85
+
+// surgeOn lights the fire up for a crack as loud as strength, from 0 to 1.
+// A quieter crack on top of a louder one's glow doesn't dim it.
+func surgeOn(strength float64) {
+	surge = math.Max(surge, strength*visualizerStrength)
+}
+
+// fadeSurge lets the glow of the last crack die away by a frame
+func fadeSurge() {
+	surge *= surgeFade
+	if surge < 0.01 {
+		surge = 0
+	}
+}
+
+// surgePalette returns the colors warmed and brightened by the current
+// surge: red and green lift toward full while blue drops, so flames flash
+// toward a hot yellow-white. Black stays black.
+func surgePalette(c []tcell.Color) []tcell.Color {
+	if surge == 0 {
+		return c
+	}
+
+	out := make([]tcell.Color, len(c))
+	for i, col := range c {
+		r, g, b := col.RGB()
+		lift := 1 + surge*0.8
+		out[i] = tcell.NewRGBColor(
+			int32(math.Min(float64(r)*lift, 255)),
+			int32(math.Min(float64(g)*(1+surge*0.5), 255)),
+			int32(float64(b)*(1-surge*0.4)),
+		)
+	}
+	return out
+}